@@ -0,0 +1,74 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_middleware
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StreamServerInterceptorFromUnary adapts a unary server interceptor to run on server streaming (1:n) RPCs.
+//
+// The unary interceptor is invoked with the single request message of the stream, once the handler
+// receives it. If the interceptor returns an error, receiving the message fails with that error, which
+// fails the stream. The context passed by the interceptor to its handler becomes the context of the
+// stream. The response returned by the handler is always nil, and a request replaced by the interceptor
+// is not propagated.
+//
+// Client streaming and bidi streaming RPCs are rejected with `Unimplemented`, as they do not have a
+// single request message. Use `grpc_selector` to install the adapted interceptor only for server
+// streaming methods if the server has others.
+func StreamServerInterceptorFromUnary(interceptor grpc.UnaryServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if info.IsClientStream {
+			return status.Errorf(codes.Unimplemented, "grpc_middleware: unary interceptor cannot be used on client streaming method %s", info.FullMethod)
+		}
+		wrapped := &unaryInterceptedServerStream{
+			ServerStream: stream,
+			ctx:          stream.Context(),
+			interceptor:  interceptor,
+			info:         &grpc.UnaryServerInfo{Server: srv, FullMethod: info.FullMethod},
+		}
+		return handler(srv, wrapped)
+	}
+}
+
+// unaryInterceptedServerStream is a grpc.ServerStream that runs a unary interceptor on the first received message.
+type unaryInterceptedServerStream struct {
+	grpc.ServerStream
+	ctx         context.Context
+	interceptor grpc.UnaryServerInterceptor
+	info        *grpc.UnaryServerInfo
+	received    bool
+}
+
+func (s *unaryInterceptedServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *unaryInterceptedServerStream) RecvMsg(m interface{}) error {
+	if s.received {
+		return s.ServerStream.RecvMsg(m)
+	}
+	s.received = true
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	handled := false
+	_, err := s.interceptor(s.ctx, m, s.info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		handled = true
+		s.ctx = ctx
+		return nil, nil
+	})
+	if err != nil {
+		return err
+	}
+	if !handled {
+		return status.Errorf(codes.Internal, "grpc_middleware: unary interceptor did not call the handler of %s", s.info.FullMethod)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStreamServerInterceptorFromUnary(t *testing.T) {
+	someService := &struct{}{}
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		require.Equal(t, someServiceName, info.FullMethod, "unary interceptor must know the method")
+		require.Equal(t, someService, info.Server, "unary interceptor must know the service")
+		require.Equal(t, "request", req, "unary interceptor must see the request message")
+		return handler(context.WithValue(ctx, "unary", someValue), req)
+	}
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		var req interface{} = "request"
+		require.NoError(t, stream.RecvMsg(req), "receiving the request must succeed")
+		requireContextValue(t, stream.Context(), "parent", "handler must know the parent context value")
+		requireContextValue(t, stream.Context(), "unary", "handler must know the unary interceptor context value")
+		require.NoError(t, stream.SendMsg("response"), "handler must be able to send stream messages")
+		return nil
+	}
+	fakeStream := &fakeServerStream{ctx: parentContext, recvMessage: "request"}
+	interceptor := StreamServerInterceptorFromUnary(unary)
+	require.NoError(t, interceptor(someService, fakeStream, parentStreamInfo, handler))
+	assert.Equal(t, "response", fakeStream.sentMessage, "handler's sent message must propagate to stream")
+}
+
+func TestStreamServerInterceptorFromUnary_FailsStream(t *testing.T) {
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return nil, status.Errorf(codes.PermissionDenied, "denied")
+	}
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		if err := stream.RecvMsg("request"); err != nil {
+			return err
+		}
+		t.Fatal("handler must not proceed once the unary interceptor fails")
+		return nil
+	}
+	interceptor := StreamServerInterceptorFromUnary(unary)
+	err := interceptor(nil, &fakeServerStream{ctx: parentContext, recvMessage: "request"}, parentStreamInfo, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "unary interceptor's error must fail the stream")
+}
+
+func TestStreamServerInterceptorFromUnary_HandlerNotCalled(t *testing.T) {
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return "cached", nil
+	}
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		return stream.RecvMsg("request")
+	}
+	interceptor := StreamServerInterceptorFromUnary(unary)
+	err := interceptor(nil, &fakeServerStream{ctx: parentContext, recvMessage: "request"}, parentStreamInfo, handler)
+	assert.Equal(t, codes.Internal, status.Code(err), "short-circuiting unary interceptors must fail the stream")
+}
+
+func TestStreamServerInterceptorFromUnary_RejectsClientStreams(t *testing.T) {
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(ctx, req)
+	}
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		t.Fatal("handler must not be called for client streams")
+		return nil
+	}
+	interceptor := StreamServerInterceptorFromUnary(unary)
+	info := &grpc.StreamServerInfo{FullMethod: someServiceName, IsClientStream: true, IsServerStream: true}
+	err := interceptor(nil, &fakeServerStream{ctx: parentContext}, info, handler)
+	assert.Equal(t, codes.Unimplemented, status.Code(err), "client streams must be rejected")
+}
@@ -0,0 +1,179 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_audit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rkollar/go-grpc-middleware"
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"github.com/rkollar/go-grpc-middleware/authz"
+	"github.com/rkollar/go-grpc-middleware/util/metautils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Decision is whether a call was allowed or denied.
+type Decision string
+
+const (
+	Allow Decision = "allow"
+	Deny  Decision = "deny"
+)
+
+// Event is the audit record of a single call.
+type Event struct {
+	// Time is when the call started.
+	Time time.Time `json:"time"`
+	// FullMethod is the full method name of the call.
+	FullMethod string `json:"method"`
+	// Principal is the caller, nil if unknown or unauthenticated.
+	Principal *grpc_auth.Principal `json:"principal,omitempty"`
+	// PeerAddress is the address of the caller.
+	PeerAddress string `json:"peer_address,omitempty"`
+	// RequestID is the request ID sent by the caller.
+	RequestID string `json:"request_id,omitempty"`
+	// Decision is Deny for calls failing with `Unauthenticated` or `PermissionDenied`, and Allow otherwise.
+	Decision Decision `json:"decision"`
+	// Reason is why the call was denied, or why it was allowed nevertheless by a dry-run policy.
+	Reason string `json:"reason,omitempty"`
+	// Code is the gRPC code the call finished with.
+	Code string `json:"code"`
+	// Duration is how long the call took.
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor that emits audit events of calls to the sink.
+func UnaryServerInterceptor(sink Sink, opts ...Option) grpc.UnaryServerInterceptor {
+	o := evaluateOptions(opts)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		rec := &record{}
+		startTime := time.Now()
+		resp, err := handler(context.WithValue(ctx, recordKey{}, rec), req)
+		o.emit(ctx, sink, rec, info.FullMethod, startTime, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a new streaming server interceptor that emits audit events of calls to the
+// sink once they finish.
+func StreamServerInterceptor(sink Sink, opts ...Option) grpc.StreamServerInterceptor {
+	o := evaluateOptions(opts)
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		rec := &record{}
+		startTime := time.Now()
+		wrapped := grpc_middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = context.WithValue(stream.Context(), recordKey{}, rec)
+		err := handler(srv, wrapped)
+		o.emit(stream.Context(), sink, rec, info.FullMethod, startTime, err)
+		return err
+	}
+}
+
+// Middleware returns a new grpc_middleware.Middleware that emits audit events of calls on the server side.
+func Middleware(sink Sink, opts ...Option) grpc_middleware.Middleware {
+	return grpc_middleware.Middleware{
+		UnaryServer:  UnaryServerInterceptor(sink, opts...),
+		StreamServer: StreamServerInterceptor(sink, opts...),
+	}
+}
+
+// AuthFunc wraps the grpc_auth.AuthFunc, recording the principal it authenticates, or the reason it rejects
+// the call, in the audit event of the call.
+func AuthFunc(authFunc grpc_auth.AuthFunc) grpc_auth.AuthFunc {
+	return func(ctx context.Context) (context.Context, error) {
+		newCtx, err := authFunc(ctx)
+		if rec, ok := ctx.Value(recordKey{}).(*record); ok {
+			if err != nil {
+				rec.setReason(status.Convert(err).Message())
+			} else if principal, ok := grpc_auth.PrincipalFromContext(newCtx); ok {
+				rec.setPrincipal(principal)
+			}
+		}
+		return newCtx, err
+	}
+}
+
+// AuthzDecisionLogger returns a grpc_authz.DecisionLogger that records the reason of denials in the audit event
+// of the call, before passing the decision on to next, unless it is nil.
+func AuthzDecisionLogger(next grpc_authz.DecisionLogger) grpc_authz.DecisionLogger {
+	return func(ctx context.Context, decision grpc_authz.Decision) {
+		if rec, ok := ctx.Value(recordKey{}).(*record); ok {
+			reason := status.Convert(decision.Err).Message()
+			if decision.Rule != "" {
+				reason += " by rule " + decision.Rule
+			} else {
+				reason += " by default"
+			}
+			if decision.DryRun {
+				reason = "dry run: " + reason
+			}
+			rec.setReason(reason)
+			if decision.Principal != nil {
+				rec.setPrincipal(decision.Principal)
+			}
+		}
+		if next != nil {
+			next(ctx, decision)
+		}
+	}
+}
+
+type recordKey struct{}
+
+// record collects what auth middleware knows about a call for its audit event.
+type record struct {
+	mu        sync.Mutex
+	principal *grpc_auth.Principal
+	reason    string
+}
+
+func (r *record) setPrincipal(principal *grpc_auth.Principal) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.principal = principal
+}
+
+func (r *record) setReason(reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reason = reason
+}
+
+func (o *options) emit(ctx context.Context, sink Sink, rec *record, fullMethod string, startTime time.Time, err error) {
+	code := status.Code(err)
+	rec.mu.Lock()
+	event := &Event{
+		Time:       startTime,
+		FullMethod: fullMethod,
+		Principal:  rec.principal,
+		RequestID:  metautils.ExtractIncoming(ctx).Get(o.requestIDHeader),
+		Decision:   Allow,
+		Reason:     rec.reason,
+		Code:       code.String(),
+		Duration:   time.Since(startTime),
+	}
+	rec.mu.Unlock()
+	if event.Principal == nil {
+		// Without a wrapped AuthFunc, audit middleware running after grpc_auth still sees the principal of the call.
+		event.Principal, _ = grpc_auth.PrincipalFromContext(ctx)
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		event.PeerAddress = p.Addr.String()
+	}
+	if code == codes.Unauthenticated || code == codes.PermissionDenied {
+		event.Decision = Deny
+		if event.Reason == "" {
+			event.Reason = status.Convert(err).Message()
+		}
+	}
+	if err := sink.Emit(ctx, event); err != nil {
+		grpclog.Errorf("grpc_audit: failed to emit audit event of %s: %v", fullMethod, err)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_audit
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/rkollar/go-grpc-middleware"
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"github.com/rkollar/go-grpc-middleware/authz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	pingMethod  = "/mwitkow.testproto.TestService/Ping"
+	adminMethod = "/mwitkow.testproto.TestService/Admin"
+)
+
+var testPeer = &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 4321}}
+
+// tokenAuthFunc authenticates the `bearer <name>` tokens of users as principals with the user role.
+func tokenAuthFunc(ctx context.Context) (context.Context, error) {
+	token, err := grpc_auth.AuthFromMD(ctx, "bearer")
+	if err != nil {
+		return nil, err
+	}
+	if token != "alice" {
+		return nil, status.Errorf(codes.Unauthenticated, "unknown token")
+	}
+	return grpc_auth.ContextWithPrincipal(ctx, &grpc_auth.Principal{Name: token, Roles: []string{"user"}}), nil
+}
+
+func auditedServer(t *testing.T, sink Sink, authzOpts ...grpc_authz.Option) grpc.UnaryServerInterceptor {
+	policy, err := grpc_authz.NewPolicy(grpc_authz.Rule{Method: adminMethod, Roles: []string{"admin"}})
+	require.NoError(t, err)
+	return grpc_middleware.ChainUnaryServer(
+		UnaryServerInterceptor(sink),
+		grpc_auth.UnaryServerInterceptor(AuthFunc(tokenAuthFunc)),
+		grpc_authz.UnaryServerInterceptor(policy, append(authzOpts, grpc_authz.WithDecisionLogger(AuthzDecisionLogger(nil)))...),
+	)
+}
+
+func unaryCall(interceptor grpc.UnaryServerInterceptor, fullMethod string, pairs ...string) error {
+	ctx := peer.NewContext(metadata.NewIncomingContext(context.TODO(), metadata.Pairs(pairs...)), testPeer)
+	_, err := interceptor(ctx, "request", &grpc.UnaryServerInfo{FullMethod: fullMethod}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Errorf(codes.NotFound, "no pong")
+	})
+	return err
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	sink := NewMemorySink()
+	interceptor := auditedServer(t, sink)
+
+	require.Equal(t, codes.NotFound, status.Code(unaryCall(interceptor, pingMethod, "authorization", "bearer alice", "x-request-id", "req-1")))
+	require.Equal(t, codes.Unauthenticated, status.Code(unaryCall(interceptor, pingMethod, "authorization", "bearer mallory")))
+	require.Equal(t, codes.PermissionDenied, status.Code(unaryCall(interceptor, adminMethod, "authorization", "bearer alice")))
+
+	events := sink.Events()
+	require.Len(t, events, 3, "every call must be audited")
+	alice := &grpc_auth.Principal{Name: "alice", Roles: []string{"user"}}
+
+	assert.Equal(t, pingMethod, events[0].FullMethod)
+	assert.Equal(t, alice, events[0].Principal, "principal must be recorded by the AuthFunc")
+	assert.Equal(t, "10.0.0.1:4321", events[0].PeerAddress)
+	assert.Equal(t, "req-1", events[0].RequestID)
+	assert.Equal(t, Allow, events[0].Decision, "calls failing after auth must be allowed")
+	assert.Equal(t, "", events[0].Reason)
+	assert.Equal(t, "NotFound", events[0].Code)
+	assert.False(t, events[0].Time.IsZero())
+
+	assert.Nil(t, events[1].Principal, "unauthenticated calls must have no principal")
+	assert.Equal(t, Deny, events[1].Decision)
+	assert.Equal(t, "unknown token", events[1].Reason, "reason of auth failures must be recorded")
+
+	assert.Equal(t, alice, events[2].Principal)
+	assert.Equal(t, Deny, events[2].Decision)
+	assert.Equal(t, "permission denied by rule "+adminMethod, events[2].Reason, "reason of authz denials must be recorded")
+}
+
+func TestUnaryServerInterceptor_DryRun(t *testing.T) {
+	sink := NewMemorySink()
+	interceptor := auditedServer(t, sink, grpc_authz.WithDryRun())
+	require.Equal(t, codes.NotFound, status.Code(unaryCall(interceptor, adminMethod, "authorization", "bearer alice")))
+
+	events := sink.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, Allow, events[0].Decision, "calls let through by dry-run policies must be allowed")
+	assert.Contains(t, events[0].Reason, "dry run", "calls let through by dry-run policies must say so")
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	sink := NewMemorySink()
+	interceptor := grpc_middleware.ChainStreamServer(StreamServerInterceptor(sink, WithRequestIDHeader("x-trace")), grpc_auth.StreamServerInterceptor(AuthFunc(tokenAuthFunc)))
+	ctx := peer.NewContext(metadata.NewIncomingContext(context.TODO(), metadata.Pairs("authorization", "bearer alice", "x-trace", "trace-1")), testPeer)
+	err := interceptor(nil, &fakeServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: pingMethod}, func(srv interface{}, stream grpc.ServerStream) error {
+		return nil
+	})
+	require.NoError(t, err)
+
+	events := sink.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, "alice", events[0].Principal.Name)
+	assert.Equal(t, "trace-1", events[0].RequestID, "request ID must be read from the configured header")
+	assert.Equal(t, Allow, events[0].Decision)
+	assert.Equal(t, "OK", events[0].Code)
+}
+
+func TestUnaryServerInterceptor_AfterAuth(t *testing.T) {
+	sink := NewMemorySink()
+	interceptor := grpc_middleware.ChainUnaryServer(grpc_auth.UnaryServerInterceptor(tokenAuthFunc), UnaryServerInterceptor(sink))
+	require.Equal(t, codes.NotFound, status.Code(unaryCall(interceptor, pingMethod, "authorization", "bearer alice")))
+
+	events := sink.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, &grpc_auth.Principal{Name: "alice", Roles: []string{"user"}}, events[0].Principal, "principal must be taken from the context without a wrapped AuthFunc")
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (f *fakeServerStream) Context() context.Context {
+	return f.ctx
+}
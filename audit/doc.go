@@ -0,0 +1,32 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`grpc_audit` records an audit trail of who called which method, and whether they were allowed to.
+
+Server Side Audit Middleware
+
+The interceptors emit a structured `Event` for every call once it finishes, with the principal, method,
+peer address, request ID, duration and the decision to allow or deny the call, along with the reason of
+denials. Calls are denied if they fail with `Unauthenticated` or `PermissionDenied`. Events are emitted
+to a `Sink`: `FileSink` appends them to a file as JSON lines, while `MemorySink` keeps them in memory for
+tests.
+
+Unlike the logging packages, which log what their loggers are configured to, the audit middleware records
+every call, independently of log levels and deciders.
+
+The audit middleware must run before the auth middleware, so that it records the calls it rejects. To learn
+who the caller is and why a call was denied, wrap the `grpc_auth.AuthFunc` with `AuthFunc`, and configure
+`grpc_authz` with `AuthzDecisionLogger`. If it runs after the auth middleware instead, it still records the
+principal stored in the context, but misses the calls rejected by auth:
+
+	authFunc := grpc_audit.AuthFunc(myAuthFunction)
+	server := grpc.NewServer(grpc_middleware.ServerOptions(
+	    grpc_audit.Middleware(sink),
+	    grpc_auth.Middleware(authFunc),
+	    grpc_authz.Middleware(policy, grpc_authz.WithDecisionLogger(grpc_audit.AuthzDecisionLogger(nil))),
+	)...)
+
+Please see examples for simple examples of use.
+*/
+package grpc_audit
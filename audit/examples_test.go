@@ -0,0 +1,30 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_audit_test
+
+import (
+	"context"
+
+	"github.com/rkollar/go-grpc-middleware"
+	"github.com/rkollar/go-grpc-middleware/audit"
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"google.golang.org/grpc"
+)
+
+func exampleAuthFunc(ctx context.Context) (context.Context, error) {
+	return grpc_auth.ContextWithPrincipal(ctx, &grpc_auth.Principal{Name: "alice"}), nil
+}
+
+// Simple example of server initialization code, appending the audit trail to a file.
+func Example_serverConfig() {
+	sink, err := grpc_audit.NewFileSink("/var/log/myservice/audit.log")
+	if err != nil {
+		panic(err)
+	}
+	defer sink.Close()
+	_ = grpc.NewServer(grpc_middleware.ServerOptions(
+		grpc_audit.Middleware(sink),
+		grpc_auth.Middleware(grpc_audit.AuthFunc(exampleAuthFunc)),
+	)...)
+}
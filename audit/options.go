@@ -0,0 +1,33 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_audit
+
+var (
+	defaultOptions = &options{
+		requestIDHeader: "x-request-id",
+	}
+)
+
+type options struct {
+	requestIDHeader string
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+// Option configures the audit interceptors.
+type Option func(*options)
+
+// WithRequestIDHeader sets the metadata header the request ID of calls is read from. Defaults to `x-request-id`.
+func WithRequestIDHeader(header string) Option {
+	return func(o *options) {
+		o.requestIDHeader = header
+	}
+}
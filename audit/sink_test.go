@@ -0,0 +1,65 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grpc_audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	event := &Event{
+		Time:       time.Unix(1600000000, 0).UTC(),
+		FullMethod: pingMethod,
+		Principal:  &grpc_auth.Principal{Name: "alice"},
+		Decision:   Allow,
+		Code:       "OK",
+		Duration:   time.Millisecond,
+	}
+	for i := 0; i < 2; i++ {
+		sink, err := NewFileSink(path)
+		require.NoError(t, err)
+		require.NoError(t, sink.Emit(context.TODO(), event))
+		require.NoError(t, sink.Close())
+	}
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	var lines int
+	for scanner.Scan() {
+		lines++
+		var got Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &got), "each line must be a JSON event")
+		assert.Equal(t, *event, got)
+	}
+	assert.Equal(t, 2, lines, "events must be appended to the existing file")
+}
+
+func TestMemorySink(t *testing.T) {
+	sink := NewMemorySink()
+	require.NoError(t, sink.Emit(context.TODO(), &Event{FullMethod: pingMethod}))
+	events := sink.Events()
+	require.Len(t, events, 1)
+	events[0].FullMethod = "changed"
+	assert.Equal(t, pingMethod, sink.Events()[0].FullMethod, "returned events must be copies")
+	sink.Reset()
+	assert.Empty(t, sink.Events())
+}
@@ -0,0 +1,118 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"github.com/rkollar/go-grpc-middleware/tags"
+	"github.com/rkollar/go-grpc-middleware/util/metautils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	secretBytes = 32
+	saltBytes   = 16
+)
+
+// Generate creates a new API key with the ID for the principal, valid until expiresAt (or forever if zero).
+//
+// It returns the key to hand out to the caller, and the record to put into the Store. The key has the form
+// `<id>.<secret>`, so the ID must not contain dots.
+func Generate(id string, principal grpc_auth.Principal, expiresAt time.Time) (string, Record, error) {
+	if id == "" || strings.Contains(id, ".") {
+		return "", Record{}, fmt.Errorf("grpc_apikey: invalid key ID %q", id)
+	}
+	secret := make([]byte, secretBytes)
+	salt := make([]byte, saltBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", Record{}, fmt.Errorf("grpc_apikey: failed to generate key: %v", err)
+	}
+	if _, err := rand.Read(salt); err != nil {
+		return "", Record{}, fmt.Errorf("grpc_apikey: failed to generate key: %v", err)
+	}
+	encodedSecret := base64.RawURLEncoding.EncodeToString(secret)
+	record := Record{
+		ID:        id,
+		Salt:      salt,
+		Hash:      hashSecret(salt, encodedSecret),
+		Principal: principal,
+		ExpiresAt: expiresAt,
+	}
+	return id + "." + encodedSecret, record, nil
+}
+
+// hashSecret hashes the secret with the salt. API key secrets are long and random, so a fast hash suffices.
+func hashSecret(salt []byte, secret string) []byte {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write([]byte(secret))
+	return h.Sum(nil)
+}
+
+// dummyRecord is verified against for unknown key IDs, so that they take as long as known ones.
+var dummyRecord = &Record{Salt: make([]byte, saltBytes), Hash: make([]byte, sha256.Size)}
+
+// AuthFunc returns a grpc_auth.AuthFunc that authenticates calls by their API key.
+//
+// The key is read from the `authorization` header with the `apikey` scheme, unless configured otherwise.
+// Keys that are unknown, do not match their hash, are expired or revoked fail the call with `Unauthenticated`.
+// The principal and expiry of the key are stored in the context, see grpc_auth.PrincipalFromContext and
+// grpc_auth.ExpiryFromContext, and the key ID is set as the `auth.api_key_id` grpc_ctxtags.
+func AuthFunc(store Store, opts ...Option) grpc_auth.AuthFunc {
+	o := evaluateOptions(opts)
+	return func(ctx context.Context) (context.Context, error) {
+		key, err := o.extractKey(ctx)
+		if err != nil {
+			return nil, err
+		}
+		id, secret := key, ""
+		if i := strings.Index(key, "."); i >= 0 {
+			id, secret = key[:i], key[i+1:]
+		}
+		record, err := store.Lookup(ctx, id)
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "grpc_apikey: failed to look up API key: %v", err)
+		}
+		matched := record != nil
+		if record == nil {
+			record = dummyRecord
+		}
+		if subtle.ConstantTimeCompare(hashSecret(record.Salt, secret), record.Hash) != 1 || !matched {
+			return nil, status.Errorf(codes.Unauthenticated, "invalid API key")
+		}
+		if record.Revoked {
+			return nil, status.Errorf(codes.Unauthenticated, "API key revoked")
+		}
+		if !record.ExpiresAt.IsZero() && !o.timeFunc().Before(record.ExpiresAt) {
+			return nil, status.Errorf(codes.Unauthenticated, "API key expired")
+		}
+		grpc_ctxtags.Extract(ctx).Set("auth.api_key_id", record.ID)
+		if !record.ExpiresAt.IsZero() {
+			ctx = grpc_auth.ContextWithExpiry(ctx, record.ExpiresAt)
+		}
+		principal := record.Principal
+		return grpc_auth.ContextWithPrincipal(ctx, &principal), nil
+	}
+}
+
+func (o *options) extractKey(ctx context.Context) (string, error) {
+	if o.header == "" {
+		return grpc_auth.AuthFromMD(ctx, o.scheme)
+	}
+	key := metautils.ExtractIncoming(ctx).Get(o.header)
+	if key == "" {
+		return "", status.Errorf(codes.Unauthenticated, "Request unauthenticated with API key")
+	}
+	return key, nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_apikey
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"github.com/rkollar/go-grpc-middleware/tags"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	partner = grpc_auth.Principal{Name: "partner", Roles: []string{"partner"}}
+	testNow = time.Unix(1600000000, 0)
+)
+
+func incomingContext(pairs ...string) context.Context {
+	return metadata.NewIncomingContext(context.TODO(), metadata.Pairs(pairs...))
+}
+
+func TestGenerate(t *testing.T) {
+	key, record, err := Generate("partner-1", partner, time.Time{})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, "partner-1."), "key must start with its ID")
+	assert.Equal(t, "partner-1", record.ID)
+	assert.Len(t, record.Salt, saltBytes)
+	assert.NotContains(t, string(record.Hash), strings.TrimPrefix(key, "partner-1."), "secret must not be stored")
+
+	other, otherRecord, err := Generate("partner-1", partner, time.Time{})
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other, "keys must be random")
+	assert.NotEqual(t, record.Salt, otherRecord.Salt, "salts must be random")
+
+	_, _, err = Generate("partner.1", partner, time.Time{})
+	assert.Error(t, err, "IDs with dots must be rejected")
+}
+
+func TestAuthFunc(t *testing.T) {
+	key, record, err := Generate("partner-1", partner, testNow.Add(time.Hour))
+	require.NoError(t, err)
+	store := NewMemoryStore(record)
+	authFunc := AuthFunc(store, WithTimeFunc(func() time.Time { return testNow }))
+
+	ctx := grpc_ctxtags.SetInContext(incomingContext("authorization", "ApiKey "+key), grpc_ctxtags.NewTags())
+	newCtx, err := authFunc(ctx)
+	require.NoError(t, err, "valid key must be accepted")
+	principal, ok := grpc_auth.PrincipalFromContext(newCtx)
+	require.True(t, ok, "principal must be stored in the context")
+	assert.Equal(t, partner, *principal)
+	assert.Equal(t, "partner-1", grpc_ctxtags.Extract(newCtx).Values()["auth.api_key_id"], "key ID must be tagged")
+	expiresAt, ok := grpc_auth.ExpiryFromContext(newCtx)
+	require.True(t, ok, "expiry of the key must be stored in the context")
+	assert.Equal(t, record.ExpiresAt, expiresAt)
+
+	for _, tcase := range []struct {
+		name string
+		ctx  context.Context
+	}{
+		{"missing", context.TODO()},
+		{"wrong scheme", incomingContext("authorization", "Bearer "+key)},
+		{"wrong secret", incomingContext("authorization", "ApiKey partner-1.wrong")},
+		{"no secret", incomingContext("authorization", "ApiKey partner-1")},
+		{"unknown ID", incomingContext("authorization", "ApiKey partner-2."+strings.TrimPrefix(key, "partner-1."))},
+	} {
+		_, err := authFunc(tcase.ctx)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), "%s key must be rejected", tcase.name)
+	}
+
+	expired := AuthFunc(store, WithTimeFunc(func() time.Time { return testNow.Add(2 * time.Hour) }))
+	_, err = expired(incomingContext("authorization", "ApiKey "+key))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "expired keys must be rejected")
+	assert.Contains(t, err.Error(), "expired")
+
+	require.True(t, store.Revoke("partner-1"))
+	_, err = authFunc(incomingContext("authorization", "ApiKey "+key))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "revoked keys must be rejected")
+	assert.Contains(t, err.Error(), "revoked")
+	assert.False(t, store.Revoke("partner-2"), "revoking unknown keys must fail")
+}
+
+func TestAuthFunc_Header(t *testing.T) {
+	key, record, err := Generate("partner-1", partner, time.Time{})
+	require.NoError(t, err)
+	authFunc := AuthFunc(NewMemoryStore(record), WithHeader("x-api-key"))
+
+	_, err = authFunc(incomingContext("x-api-key", key))
+	assert.NoError(t, err, "key must be read from the header")
+	_, err = authFunc(incomingContext("authorization", "ApiKey "+key))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "key must not be read from other headers")
+}
+
+type failingStore struct{}
+
+func (failingStore) Lookup(context.Context, string) (*Record, error) {
+	return nil, errors.New("database down")
+}
+
+func TestAuthFunc_StoreError(t *testing.T) {
+	_, err := AuthFunc(failingStore{})(incomingContext("authorization", "ApiKey partner-1.secret"))
+	assert.Equal(t, codes.Unavailable, status.Code(err), "store errors must not be reported as invalid keys")
+}
@@ -0,0 +1,20 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`grpc_apikey` provides a `grpc_auth.AuthFunc` that authenticates callers by API keys.
+
+API Key Authentication
+
+API keys have the form `<id>.<secret>` and are created with `Generate`, which returns the key to hand
+out and the `Record` to store. Only a salted hash of the secret is stored, and keys are verified in
+constant time. Records map keys to the `grpc_auth.Principal` they authenticate as, and can expire or be
+revoked.
+
+`AuthFunc` reads the key from the `:authorization` header with the `apikey` scheme, or from another
+metadata header with `WithHeader`, and looks it up in a `Store`. `MemoryStore` keeps the records in
+memory, while `FileStore` reads them from a JSON file and picks up changes to it.
+
+Please see examples for simple examples of use.
+*/
+package grpc_apikey
@@ -0,0 +1,37 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_apikey_test
+
+import (
+	"time"
+
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"github.com/rkollar/go-grpc-middleware/auth/apikey"
+	"google.golang.org/grpc"
+)
+
+// Simple example of server initialization code, reading API keys from the `x-api-key` header.
+func Example_serverConfig() {
+	store, err := grpc_apikey.NewFileStore("/etc/myservice/api_keys.json", 30*time.Second)
+	if err != nil {
+		panic(err)
+	}
+	authFunc := grpc_apikey.AuthFunc(store, grpc_apikey.WithHeader("x-api-key"))
+	_ = grpc.NewServer(
+		grpc.StreamInterceptor(grpc_auth.StreamServerInterceptor(authFunc)),
+		grpc.UnaryInterceptor(grpc_auth.UnaryServerInterceptor(authFunc)),
+	)
+}
+
+// Simple example of issuing a key to a partner.
+func ExampleGenerate() {
+	store := grpc_apikey.NewMemoryStore()
+	key, record, err := grpc_apikey.Generate("acme", grpc_auth.Principal{Name: "acme", Roles: []string{"partner"}}, time.Now().AddDate(1, 0, 0))
+	if err != nil {
+		panic(err)
+	}
+	store.Put(record)
+	// Hand out the key to the partner. It cannot be recovered later, as only its hash is stored.
+	_ = key
+}
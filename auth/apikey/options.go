@@ -0,0 +1,54 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_apikey
+
+import (
+	"time"
+)
+
+var (
+	defaultOptions = &options{
+		scheme:   "apikey",
+		timeFunc: time.Now,
+	}
+)
+
+type options struct {
+	header   string
+	scheme   string
+	timeFunc func() time.Time
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+// Option configures how API keys are read and verified.
+type Option func(*options)
+
+// WithHeader reads the key from the metadata header, e.g. `x-api-key`, instead of the `authorization` header.
+func WithHeader(header string) Option {
+	return func(o *options) {
+		o.header = header
+	}
+}
+
+// WithScheme sets the scheme of the `authorization` header the key is read from. Defaults to `apikey`.
+func WithScheme(scheme string) Option {
+	return func(o *options) {
+		o.scheme = scheme
+	}
+}
+
+// WithTimeFunc sets the clock that key expiry is checked against. Defaults to time.Now.
+func WithTimeFunc(f func() time.Time) Option {
+	return func(o *options) {
+		o.timeFunc = f
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_apikey
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rkollar/go-grpc-middleware/auth"
+)
+
+// Record is a stored API key. The secret part of the key is only kept as a salted hash.
+type Record struct {
+	// ID is the public part of the key, used to look it up.
+	ID string `json:"id"`
+	// Salt is the random salt of the hash.
+	Salt []byte `json:"salt"`
+	// Hash is the SHA-256 hash of the salt followed by the secret part of the key.
+	Hash []byte `json:"hash"`
+	// Principal is the caller the key authenticates as.
+	Principal grpc_auth.Principal `json:"principal"`
+	// ExpiresAt is when the key expires. The key never expires if it is zero.
+	ExpiresAt time.Time `json:"expires_at"`
+	// Revoked is set for keys that must not be accepted anymore.
+	Revoked bool `json:"revoked,omitempty"`
+}
+
+// Store looks up API keys by their ID.
+type Store interface {
+	// Lookup returns the record of the key with the ID, or nil if there is none.
+	Lookup(ctx context.Context, id string) (*Record, error)
+}
+
+// MemoryStore is a Store keeping the records in memory. It is safe for concurrent use.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records map[string]Record
+}
+
+// NewMemoryStore creates a MemoryStore holding the records.
+func NewMemoryStore(records ...Record) *MemoryStore {
+	s := &MemoryStore{records: make(map[string]Record, len(records))}
+	for _, r := range records {
+		s.records[r.ID] = r
+	}
+	return s
+}
+
+// Lookup implements Store.
+func (s *MemoryStore) Lookup(_ context.Context, id string) (*Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.records[id]
+	if !ok {
+		return nil, nil
+	}
+	return &r, nil
+}
+
+// Put adds the record, replacing any record with the same ID.
+func (s *MemoryStore) Put(record Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[record.ID] = record
+}
+
+// Revoke marks the key with the ID as revoked. It returns false if there is no such key.
+func (s *MemoryStore) Revoke(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.records[id]
+	if !ok {
+		return false
+	}
+	r.Revoked = true
+	s.records[id] = r
+	return true
+}
+
+type storeDocument struct {
+	Keys []Record `json:"keys"`
+}
+
+// FileStore is a Store backed by a JSON file of the form `{"keys": [<Record>...]}`.
+//
+// The file is reloaded when its modification time changes, checked at most once per reload interval, so
+// that keys can be added and revoked without restarting the server. If reloading fails, the previously
+// loaded records stay in use.
+type FileStore struct {
+	path           string
+	reloadInterval time.Duration
+
+	mu        sync.Mutex
+	records   *MemoryStore
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// NewFileStore creates a FileStore, loading the file.
+func NewFileStore(path string, reloadInterval time.Duration) (*FileStore, error) {
+	s := &FileStore{path: path, reloadInterval: reloadInterval}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Lookup implements Store.
+func (s *FileStore) Lookup(ctx context.Context, id string) (*Record, error) {
+	s.mu.Lock()
+	if time.Since(s.checkedAt) >= s.reloadInterval {
+		// Errors keep the records loaded last, and are retried after the reload interval.
+		_ = s.reload()
+	}
+	records := s.records
+	s.mu.Unlock()
+	return records.Lookup(ctx, id)
+}
+
+// reload reads the file if it changed since it was last read.
+func (s *FileStore) reload() error {
+	s.checkedAt = time.Now()
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return fmt.Errorf("grpc_apikey: failed to read key file: %v", err)
+	}
+	if s.records != nil && info.ModTime().Equal(s.modTime) {
+		return nil
+	}
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("grpc_apikey: failed to read key file: %v", err)
+	}
+	var doc storeDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("grpc_apikey: malformed key file: %v", err)
+	}
+	s.records = NewMemoryStore(doc.Keys...)
+	s.modTime = info.ModTime()
+	return nil
+}
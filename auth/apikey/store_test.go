@@ -0,0 +1,78 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_apikey
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKeyFile(t *testing.T, path string, modTime time.Time, records ...Record) {
+	data, err := json.Marshal(storeDocument{Keys: records})
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(path, data, 0600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grpc_apikey")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "keys.json")
+
+	key, record, err := Generate("partner-1", partner, time.Time{})
+	require.NoError(t, err)
+	writeKeyFile(t, path, testNow, record)
+
+	store, err := NewFileStore(path, 0)
+	require.NoError(t, err)
+	found, err := store.Lookup(context.TODO(), "partner-1")
+	require.NoError(t, err)
+	require.NotNil(t, found, "stored key must be found")
+	assert.Equal(t, record.Hash, found.Hash, "hash must survive the round trip through the file")
+	assert.Equal(t, partner, found.Principal, "principal must survive the round trip through the file")
+
+	_, err = AuthFunc(store)(incomingContext("authorization", "ApiKey "+key))
+	assert.NoError(t, err, "key from the file must be accepted")
+
+	record.Revoked = true
+	writeKeyFile(t, path, testNow.Add(time.Second), record)
+	found, err = store.Lookup(context.TODO(), "partner-1")
+	require.NoError(t, err)
+	assert.True(t, found.Revoked, "changes to the file must be picked up")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("not json"), 0600))
+	found, err = store.Lookup(context.TODO(), "partner-1")
+	require.NoError(t, err, "broken files must not fail lookups")
+	assert.True(t, found.Revoked, "records loaded last must stay in use")
+
+	_, err = NewFileStore(filepath.Join(dir, "missing.json"), time.Minute)
+	assert.Error(t, err)
+}
+
+func TestFileStore_ReloadInterval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grpc_apikey")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "keys.json")
+
+	_, record, err := Generate("partner-1", partner, time.Time{})
+	require.NoError(t, err)
+	writeKeyFile(t, path, testNow, record)
+	store, err := NewFileStore(path, time.Hour)
+	require.NoError(t, err)
+
+	writeKeyFile(t, path, testNow.Add(time.Second))
+	found, err := store.Lookup(context.TODO(), "partner-1")
+	require.NoError(t, err)
+	assert.NotNil(t, found, "file must not be reloaded before the reload interval")
+}
@@ -5,8 +5,9 @@ package grpc_auth
 
 import (
 	"context"
+	"time"
 
-	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/rkollar/go-grpc-middleware"
 	"google.golang.org/grpc"
 )
 
@@ -31,38 +32,59 @@ type ServiceAuthFuncOverride interface {
 	AuthFuncOverride(ctx context.Context, fullMethodName string) (context.Context, error)
 }
 
+// NoAuth is an AuthFunc that lets all calls through, e.g. to exempt methods from auth with WithMethodOverrides.
+func NoAuth(ctx context.Context) (context.Context, error) {
+	return ctx, nil
+}
+
 // UnaryServerInterceptor returns a new unary server interceptors that performs per-request auth.
-func UnaryServerInterceptor(authFunc AuthFunc) grpc.UnaryServerInterceptor {
+func UnaryServerInterceptor(authFunc AuthFunc, opts ...Option) grpc.UnaryServerInterceptor {
+	o := evaluateOptions(opts)
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		var newCtx context.Context
-		var err error
-		if overrideSrv, ok := info.Server.(ServiceAuthFuncOverride); ok {
-			newCtx, err = overrideSrv.AuthFuncOverride(ctx, info.FullMethod)
-		} else {
-			newCtx, err = authFunc(ctx)
-		}
+		newCtx, err := o.authenticate(ctx, authFunc, info.Server, info.FullMethod)
 		if err != nil {
 			return nil, err
 		}
+		if expiresAt, ok := ExpiryFromContext(newCtx); ok && o.enforceExpiry && !time.Now().Before(expiresAt) {
+			return nil, errCredentialsExpired
+		}
 		return handler(newCtx, req)
 	}
 }
 
 // StreamServerInterceptor returns a new unary server interceptors that performs per-request auth.
-func StreamServerInterceptor(authFunc AuthFunc) grpc.StreamServerInterceptor {
+func StreamServerInterceptor(authFunc AuthFunc, opts ...Option) grpc.StreamServerInterceptor {
+	o := evaluateOptions(opts)
 	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		var newCtx context.Context
-		var err error
-		if overrideSrv, ok := srv.(ServiceAuthFuncOverride); ok {
-			newCtx, err = overrideSrv.AuthFuncOverride(stream.Context(), info.FullMethod)
-		} else {
-			newCtx, err = authFunc(stream.Context())
-		}
+		newCtx, err := o.authenticate(stream.Context(), authFunc, srv, info.FullMethod)
 		if err != nil {
 			return err
 		}
+		if expiresAt, ok := ExpiryFromContext(newCtx); ok && o.enforceExpiry {
+			return o.serveUntilExpiry(srv, stream, handler, newCtx, expiresAt)
+		}
 		wrapped := grpc_middleware.WrapServerStream(stream)
 		wrapped.WrappedContext = newCtx
 		return handler(srv, wrapped)
 	}
 }
+
+// Middleware returns a new grpc_middleware.Middleware that performs per-request auth on the server side.
+func Middleware(authFunc AuthFunc, opts ...Option) grpc_middleware.Middleware {
+	return grpc_middleware.Middleware{
+		UnaryServer:  UnaryServerInterceptor(authFunc, opts...),
+		StreamServer: StreamServerInterceptor(authFunc, opts...),
+	}
+}
+
+// authenticate calls the AuthFunc that applies to the method: its override in WithMethodOverrides, the
+// ServiceAuthFuncOverride of the service, or the global one, in that order of precedence.
+func (o *options) authenticate(ctx context.Context, authFunc AuthFunc, srv interface{}, fullMethod string) (context.Context, error) {
+	if override, ok := o.methodOverrides[fullMethod]; ok {
+		return override(ctx)
+	}
+	if overrideSrv, ok := srv.(ServiceAuthFuncOverride); ok {
+		return overrideSrv.AuthFuncOverride(ctx, fullMethod)
+	}
+	return authFunc(ctx)
+}
@@ -0,0 +1,197 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_auth
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/rkollar/go-grpc-middleware/util/metautils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CachingAuthFunc wraps an expensive AuthFunc, e.g. one calling a token introspection endpoint, caching its
+// results by the credential of the call.
+//
+// Only the result of the AuthFunc is cached, not the context it returns, as the latter belongs to a single
+// call. By default the result is the Principal and the expiry it stores in the context, see WithCacheResult
+// to cache other values. Successful results are cached for the TTL, or until they expire if that is earlier. Failures with
+// `Unauthenticated` or `PermissionDenied` are cached for the negative TTL, which disables it by default.
+// Concurrent calls with the same credential share a single call of the AuthFunc.
+func CachingAuthFunc(authFunc AuthFunc, opts ...CacheOption) AuthFunc {
+	c := &authCache{
+		authFunc: authFunc,
+		opts:     evaluateCacheOptions(opts),
+		entries:  map[string]*list.Element{},
+		lru:      list.New(),
+		calls:    map[string]*authCall{},
+	}
+	return c.authenticate
+}
+
+// DefaultCacheKey is the default key of CachingAuthFunc: the `authorization` header of the call.
+func DefaultCacheKey(ctx context.Context) (string, bool) {
+	val := metautils.ExtractIncoming(ctx).Get(headerAuthorize)
+	return val, val != ""
+}
+
+// principalResult is the default result of CachingAuthFunc.
+type principalResult struct {
+	principal *Principal
+	expiresAt time.Time
+}
+
+// principalFromContext is the default result extractor of CachingAuthFunc.
+func principalFromContext(ctx context.Context) (interface{}, time.Time, bool) {
+	principal, ok := PrincipalFromContext(ctx)
+	expiresAt, _ := ExpiryFromContext(ctx)
+	return principalResult{principal: principal, expiresAt: expiresAt}, expiresAt, ok
+}
+
+// contextWithPrincipal is the default result applier of CachingAuthFunc.
+func contextWithPrincipal(ctx context.Context, result interface{}) context.Context {
+	r := result.(principalResult)
+	if !r.expiresAt.IsZero() {
+		ctx = ContextWithExpiry(ctx, r.expiresAt)
+	}
+	return ContextWithPrincipal(ctx, r.principal)
+}
+
+type authCache struct {
+	authFunc AuthFunc
+	opts     *cacheOptions
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	calls   map[string]*authCall
+}
+
+type authCacheEntry struct {
+	key       string
+	result    interface{}
+	err       error
+	expiresAt time.Time
+}
+
+// authCall is an in-flight call of the AuthFunc, shared by concurrent calls with the same key.
+type authCall struct {
+	done      chan struct{}
+	result    interface{}
+	expiresAt time.Time
+	err       error
+	// shared is set if the outcome applies to the waiting calls too, rather than to the calling one only.
+	shared bool
+}
+
+func (c *authCache) authenticate(ctx context.Context) (context.Context, error) {
+	credential, ok := c.opts.keyFunc(ctx)
+	if !ok {
+		return c.authFunc(ctx)
+	}
+	// Hash the credential, so that the cache does not keep secrets in memory.
+	sum := sha256.Sum256([]byte(credential))
+	key := string(sum[:])
+
+	c.mu.Lock()
+	if entry, ok := c.lookup(key); ok {
+		c.mu.Unlock()
+		if entry.err != nil {
+			return nil, entry.err
+		}
+		return c.opts.apply(ctx, entry.result), nil
+	}
+	if call, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, contextError(ctx)
+		}
+		if !call.shared {
+			return c.authFunc(ctx)
+		}
+		if call.err != nil {
+			return nil, call.err
+		}
+		return c.opts.apply(ctx, call.result), nil
+	}
+	call := &authCall{done: make(chan struct{})}
+	c.calls[key] = call
+	c.mu.Unlock()
+
+	var newCtx context.Context
+	var err error
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, key)
+		if call.shared {
+			c.store(key, call)
+		}
+		c.mu.Unlock()
+		close(call.done)
+	}()
+	newCtx, err = c.authFunc(ctx)
+	switch {
+	case err == nil:
+		call.result, call.expiresAt, call.shared = c.opts.extract(newCtx)
+	case status.Code(err) == codes.Unauthenticated || status.Code(err) == codes.PermissionDenied:
+		call.err, call.shared = err, true
+	}
+	return newCtx, err
+}
+
+// lookup returns the unexpired entry of the key, marking it as recently used. It must be called with mu held.
+func (c *authCache) lookup(key string) (*authCacheEntry, bool) {
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*authCacheEntry)
+	if !c.opts.timeFunc().Before(entry.expiresAt) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return entry, true
+}
+
+// store caches the outcome of the call, evicting the least recently used entry if the cache is full. It must
+// be called with mu held.
+func (c *authCache) store(key string, call *authCall) {
+	now := c.opts.timeFunc()
+	entry := &authCacheEntry{key: key, result: call.result, err: call.err}
+	if call.err != nil {
+		entry.expiresAt = now.Add(c.opts.negativeTTL)
+	} else {
+		entry.expiresAt = now.Add(c.opts.ttl)
+		if !call.expiresAt.IsZero() && call.expiresAt.Before(entry.expiresAt) {
+			entry.expiresAt = call.expiresAt
+		}
+	}
+	if !now.Before(entry.expiresAt) || c.opts.size <= 0 {
+		return
+	}
+	if elem, ok := c.entries[key]; ok {
+		c.lru.Remove(elem)
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.opts.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*authCacheEntry).key)
+	}
+}
+
+func contextError(ctx context.Context) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return status.Error(codes.DeadlineExceeded, ctx.Err().Error())
+	}
+	return status.Error(codes.Canceled, ctx.Err().Error())
+}
@@ -0,0 +1,199 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_auth_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"github.com/rkollar/go-grpc-middleware/util/metautils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func ctxWithAuthorization(authorization string) context.Context {
+	return metautils.NiceMD{}.Add("authorization", authorization).ToIncoming(context.TODO())
+}
+
+// countingAuthFunc authenticates the `bearer <name>` tokens of the users, counting its calls.
+func countingAuthFunc(calls *int32, users ...string) grpc_auth.AuthFunc {
+	return func(ctx context.Context) (context.Context, error) {
+		atomic.AddInt32(calls, 1)
+		token, err := grpc_auth.AuthFromMD(ctx, "bearer")
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			if u == token {
+				return grpc_auth.ContextWithPrincipal(ctx, &grpc_auth.Principal{Name: u}), nil
+			}
+		}
+		return nil, status.Errorf(codes.Unauthenticated, "unknown token")
+	}
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestCachingAuthFunc(t *testing.T) {
+	var calls int32
+	clock := &fakeClock{now: time.Now()}
+	authFunc := grpc_auth.CachingAuthFunc(countingAuthFunc(&calls, "alice", "bob"), grpc_auth.WithCacheTTL(time.Minute), grpc_auth.WithCacheTimeFunc(clock.Now))
+
+	for i := 0; i < 3; i++ {
+		ctx, err := authFunc(ctxWithAuthorization("bearer alice"))
+		require.NoError(t, err)
+		principal, ok := grpc_auth.PrincipalFromContext(ctx)
+		require.True(t, ok, "cached calls must have the Principal in their context")
+		assert.Equal(t, "alice", principal.Name)
+	}
+	assert.EqualValues(t, 1, calls, "results must be cached by credential")
+
+	ctx, err := authFunc(ctxWithAuthorization("bearer bob"))
+	require.NoError(t, err)
+	principal, _ := grpc_auth.PrincipalFromContext(ctx)
+	assert.Equal(t, "bob", principal.Name, "other credentials must not share cached results")
+	assert.EqualValues(t, 2, calls)
+
+	clock.now = clock.now.Add(time.Minute)
+	_, err = authFunc(ctxWithAuthorization("bearer alice"))
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, calls, "results must expire after the TTL")
+
+	_, err = authFunc(context.TODO())
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = authFunc(context.TODO())
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.EqualValues(t, 5, calls, "calls without a credential must bypass the cache")
+}
+
+type tokenExpiryKey struct{}
+
+func TestCachingAuthFunc_ResultExpiry(t *testing.T) {
+	var calls int32
+	clock := &fakeClock{now: time.Now()}
+	expiry := clock.now.Add(10 * time.Second)
+	authFunc := grpc_auth.CachingAuthFunc(
+		func(ctx context.Context) (context.Context, error) {
+			atomic.AddInt32(&calls, 1)
+			return context.WithValue(ctx, tokenExpiryKey{}, expiry), nil
+		},
+		grpc_auth.WithCacheResult(
+			func(authedCtx context.Context) (interface{}, time.Time, bool) {
+				expiry := authedCtx.Value(tokenExpiryKey{}).(time.Time)
+				return expiry, expiry, true
+			},
+			func(ctx context.Context, result interface{}) context.Context {
+				return context.WithValue(ctx, tokenExpiryKey{}, result)
+			},
+		),
+		grpc_auth.WithCacheTimeFunc(clock.Now),
+	)
+
+	_, err := authFunc(ctxWithAuthorization("bearer token"))
+	require.NoError(t, err)
+	clock.now = clock.now.Add(9 * time.Second)
+	ctx, err := authFunc(ctxWithAuthorization("bearer token"))
+	require.NoError(t, err)
+	assert.Equal(t, expiry, ctx.Value(tokenExpiryKey{}), "cached results must be applied to the context")
+	assert.EqualValues(t, 1, calls)
+
+	clock.now = clock.now.Add(time.Second)
+	_, err = authFunc(ctxWithAuthorization("bearer token"))
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, calls, "results must expire with the token, before the TTL")
+}
+
+func TestCachingAuthFunc_NegativeCaching(t *testing.T) {
+	var calls int32
+	clock := &fakeClock{now: time.Now()}
+	authFunc := grpc_auth.CachingAuthFunc(countingAuthFunc(&calls, "alice"), grpc_auth.WithNegativeCacheTTL(5*time.Second), grpc_auth.WithCacheTimeFunc(clock.Now))
+
+	for i := 0; i < 3; i++ {
+		_, err := authFunc(ctxWithAuthorization("bearer mallory"))
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), "cached failures must be returned")
+	}
+	assert.EqualValues(t, 1, calls, "failures must be cached for the negative TTL")
+	clock.now = clock.now.Add(5 * time.Second)
+	_, err := authFunc(ctxWithAuthorization("bearer mallory"))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.EqualValues(t, 2, calls, "failures must expire after the negative TTL")
+
+	calls = 0
+	authFunc = grpc_auth.CachingAuthFunc(func(ctx context.Context) (context.Context, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, status.Errorf(codes.Unavailable, "introspection endpoint down")
+	}, grpc_auth.WithNegativeCacheTTL(5*time.Second))
+	authFunc(ctxWithAuthorization("bearer alice"))
+	authFunc(ctxWithAuthorization("bearer alice"))
+	assert.EqualValues(t, 2, calls, "failures other than Unauthenticated and PermissionDenied must not be cached")
+}
+
+func TestCachingAuthFunc_LRU(t *testing.T) {
+	var calls int32
+	authFunc := grpc_auth.CachingAuthFunc(countingAuthFunc(&calls, "alice", "bob", "carol"), grpc_auth.WithCacheSize(2))
+	for _, user := range []string{"alice", "bob", "alice", "carol"} {
+		_, err := authFunc(ctxWithAuthorization("bearer " + user))
+		require.NoError(t, err)
+	}
+	assert.EqualValues(t, 3, calls)
+
+	_, err := authFunc(ctxWithAuthorization("bearer alice"))
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, calls, "recently used results must be kept")
+	_, err = authFunc(ctxWithAuthorization("bearer bob"))
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, calls, "least recently used results must be evicted")
+}
+
+func TestCachingAuthFunc_ConcurrentCallsShareLookup(t *testing.T) {
+	var calls int32
+	started, release := make(chan struct{}), make(chan struct{})
+	lookup := countingAuthFunc(&calls, "alice")
+	authFunc := grpc_auth.CachingAuthFunc(func(ctx context.Context) (context.Context, error) {
+		if atomic.LoadInt32(&calls) == 0 {
+			close(started)
+		}
+		<-release
+		return lookup(ctx)
+	})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	call := func() {
+		defer wg.Done()
+		ctx, err := authFunc(ctxWithAuthorization("bearer alice"))
+		if err == nil {
+			if _, ok := grpc_auth.PrincipalFromContext(ctx); !ok {
+				err = status.Errorf(codes.Internal, "no principal")
+			}
+		}
+		errs <- err
+	}
+	wg.Add(1)
+	go call()
+	<-started
+	for i := 1; i < cap(errs); i++ {
+		wg.Add(1)
+		go call()
+	}
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err, "all concurrent calls must be authenticated")
+	}
+	assert.EqualValues(t, 1, calls, "concurrent calls with the same credential must share a single lookup")
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_auth
+
+import (
+	"context"
+	"crypto/x509"
+	"net/url"
+
+	"github.com/rkollar/go-grpc-middleware/tags"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// PeerIdentity is the identity of a peer, taken from its verified TLS client certificate.
+type PeerIdentity struct {
+	// CommonName is the common name of the certificate subject.
+	CommonName string
+	// DNSNames are the DNS subject alternative names of the certificate.
+	DNSNames []string
+	// URIs are the URI subject alternative names of the certificate.
+	URIs []*url.URL
+	// SPIFFEID is the SPIFFE ID of the certificate, if it is a valid X.509-SVID, and nil otherwise.
+	SPIFFEID *url.URL
+	// Certificate is the verified leaf certificate of the peer.
+	Certificate *x509.Certificate
+}
+
+type peerIdentityMarker struct{}
+
+var peerIdentityMarkerKey = &peerIdentityMarker{}
+
+// PeerIdentityFromContext returns the peer identity verified by CertificateAuthFunc for the call.
+func PeerIdentityFromContext(ctx context.Context) (*PeerIdentity, bool) {
+	identity, ok := ctx.Value(peerIdentityMarkerKey).(*PeerIdentity)
+	return identity, ok
+}
+
+// PeerIdentityFromCertificate extracts the identity from a certificate.
+func PeerIdentityFromCertificate(cert *x509.Certificate) *PeerIdentity {
+	identity := &PeerIdentity{
+		CommonName:  cert.Subject.CommonName,
+		DNSNames:    cert.DNSNames,
+		URIs:        cert.URIs,
+		Certificate: cert,
+	}
+	// An X.509-SVID carries exactly one URI SAN, which is its SPIFFE ID.
+	if len(cert.URIs) == 1 && isSPIFFEID(cert.URIs[0]) {
+		identity.SPIFFEID = cert.URIs[0]
+	}
+	return identity
+}
+
+// Principal returns the principal of the identity, named by its SPIFFE ID, or by its first DNS subject
+// alternative name or its common name if the certificate is not an X.509-SVID.
+func (i *PeerIdentity) Principal() *Principal {
+	switch {
+	case i.SPIFFEID != nil:
+		return &Principal{Name: i.SPIFFEID.String()}
+	case len(i.DNSNames) > 0:
+		return &Principal{Name: i.DNSNames[0]}
+	}
+	return &Principal{Name: i.CommonName}
+}
+
+func isSPIFFEID(u *url.URL) bool {
+	return u.Scheme == "spiffe" && u.Host != "" && u.User == nil && u.Port() == "" && u.RawQuery == "" && u.Fragment == ""
+}
+
+// CertificateAuthFunc returns an AuthFunc that authenticates peers by their TLS client certificate.
+//
+// The server must be configured to verify client certificates, e.g. with `tls.VerifyClientCertIfGiven`,
+// as only certificates with a verified chain are accepted. Calls without one fail with `Unauthenticated`.
+// If any allowlist or trust domain is configured, identities matching none of them fail with
+// `PermissionDenied`. Otherwise, all verified identities are allowed.
+//
+// The identity is stored in the context, see PeerIdentityFromContext, along with its Principal, and its
+// common name and SPIFFE ID are set as the `peer.cert.cn` and `peer.spiffe_id` grpc_ctxtags.
+func CertificateAuthFunc(opts ...CertificateOption) AuthFunc {
+	o := evaluateCertificateOptions(opts)
+	return func(ctx context.Context) (context.Context, error) {
+		p, ok := peer.FromContext(ctx)
+		if !ok {
+			return nil, status.Errorf(codes.Unauthenticated, "no peer information")
+		}
+		tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+		if !ok {
+			return nil, status.Errorf(codes.Unauthenticated, "connection is not secured with TLS")
+		}
+		if len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+			return nil, status.Errorf(codes.Unauthenticated, "no verified client certificate")
+		}
+		identity := PeerIdentityFromCertificate(tlsInfo.State.VerifiedChains[0][0])
+		if !o.allows(identity) {
+			return nil, status.Errorf(codes.PermissionDenied, "client certificate identity not allowed")
+		}
+		tags := grpc_ctxtags.Extract(ctx)
+		if identity.CommonName != "" {
+			tags.Set("peer.cert.cn", identity.CommonName)
+		}
+		if identity.SPIFFEID != nil {
+			tags.Set("peer.spiffe_id", identity.SPIFFEID.String())
+		}
+		ctx = ContextWithPrincipal(ctx, identity.Principal())
+		return context.WithValue(ctx, peerIdentityMarkerKey, identity), nil
+	}
+}
+
+// allows returns whether the identity matches any of the allowlists or trust domains.
+func (o *certificateOptions) allows(identity *PeerIdentity) bool {
+	if len(o.commonNames) == 0 && len(o.dnsNames) == 0 && len(o.spiffeIDs) == 0 && len(o.trustDomains) == 0 {
+		return true
+	}
+	if identity.CommonName != "" && containsString(o.commonNames, identity.CommonName) {
+		return true
+	}
+	for _, name := range identity.DNSNames {
+		if containsString(o.dnsNames, name) {
+			return true
+		}
+	}
+	if identity.SPIFFEID != nil {
+		if containsString(o.spiffeIDs, identity.SPIFFEID.String()) || containsString(o.trustDomains, identity.SPIFFEID.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_auth_test
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"flag"
+	"net/url"
+	"testing"
+
+	"github.com/rkollar/go-grpc-middleware"
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"github.com/rkollar/go-grpc-middleware/tags"
+	"github.com/rkollar/go-grpc-middleware/testing"
+	pb_testproto "github.com/rkollar/go-grpc-middleware/testing/testproto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func mustParseURL(raw string) *url.URL {
+	u, err := url.Parse(raw)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+func TestPeerIdentityFromCertificate(t *testing.T) {
+	identity := grpc_auth.PeerIdentityFromCertificate(&x509.Certificate{
+		Subject:  pkix.Name{CommonName: "billing"},
+		DNSNames: []string{"billing.example.org"},
+		URIs:     []*url.URL{mustParseURL("spiffe://example.org/billing")},
+	})
+	assert.Equal(t, "billing", identity.CommonName)
+	assert.Equal(t, []string{"billing.example.org"}, identity.DNSNames)
+	require.NotNil(t, identity.SPIFFEID, "single spiffe URI SAN must be the SPIFFE ID")
+	assert.Equal(t, "spiffe://example.org/billing", identity.SPIFFEID.String())
+	assert.Equal(t, &grpc_auth.Principal{Name: "spiffe://example.org/billing"}, identity.Principal(), "principal must be named by the SPIFFE ID")
+
+	identity = grpc_auth.PeerIdentityFromCertificate(&x509.Certificate{Subject: pkix.Name{CommonName: "ops"}, DNSNames: []string{"ops.example.com"}})
+	assert.Equal(t, &grpc_auth.Principal{Name: "ops.example.com"}, identity.Principal(), "principal must be named by the DNS SAN without a SPIFFE ID")
+	identity = grpc_auth.PeerIdentityFromCertificate(&x509.Certificate{Subject: pkix.Name{CommonName: "admin"}})
+	assert.Equal(t, &grpc_auth.Principal{Name: "admin"}, identity.Principal(), "principal must be named by the common name without SANs")
+
+	for _, uris := range [][]*url.URL{
+		{mustParseURL("spiffe://example.org/billing"), mustParseURL("spiffe://example.org/other")},
+		{mustParseURL("https://example.org/billing")},
+		{mustParseURL("spiffe://example.org:8080/billing")},
+	} {
+		identity := grpc_auth.PeerIdentityFromCertificate(&x509.Certificate{URIs: uris})
+		assert.Nil(t, identity.SPIFFEID, "%v must not be a SPIFFE ID", uris)
+		assert.Equal(t, uris, identity.URIs, "URI SANs must be extracted")
+	}
+}
+
+func TestCertificateAuthFunc_NoPeer(t *testing.T) {
+	_, err := grpc_auth.CertificateAuthFunc()(context.TODO())
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+type identityPingService struct {
+	pb_testproto.TestServiceServer
+	T *testing.T
+}
+
+func (s *identityPingService) Ping(ctx context.Context, ping *pb_testproto.PingRequest) (*pb_testproto.PingResponse, error) {
+	identity, ok := grpc_auth.PeerIdentityFromContext(ctx)
+	require.True(s.T, ok, "identity must be stored in the context")
+	principal, ok := grpc_auth.PrincipalFromContext(ctx)
+	require.True(s.T, ok, "principal must be stored in the context")
+	assert.Equal(s.T, identity.Principal(), principal)
+	tags := grpc_ctxtags.Extract(ctx).Values()
+	if identity.SPIFFEID != nil {
+		assert.Equal(s.T, identity.SPIFFEID.String(), tags["peer.spiffe_id"], "SPIFFE ID must be tagged")
+		return &pb_testproto.PingResponse{Value: identity.SPIFFEID.String()}, nil
+	}
+	assert.Equal(s.T, identity.CommonName, tags["peer.cert.cn"], "common name must be tagged")
+	return &pb_testproto.PingResponse{Value: identity.CommonName}, nil
+}
+
+func TestCertificateAuthTestSuite(t *testing.T) {
+	if f := flag.Lookup("use_tls"); f != nil && f.Value.String() != "true" {
+		t.Skip("client certificates require TLS")
+	}
+	authFunc := grpc_auth.CertificateAuthFunc(
+		grpc_auth.WithTrustDomains("example.org"),
+		grpc_auth.WithAllowedCommonNames("admin"),
+		grpc_auth.WithAllowedDNSNames("ops.example.com"),
+	)
+	s := &CertificateAuthTestSuite{
+		InterceptorTestSuite: &grpc_testing.InterceptorTestSuite{
+			TestService: &identityPingService{&grpc_testing.TestPingService{T: t}, t},
+			ServerOpts: []grpc.ServerOption{
+				grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(grpc_ctxtags.UnaryServerInterceptor(), grpc_auth.UnaryServerInterceptor(authFunc))),
+			},
+		},
+	}
+	suite.Run(t, s)
+}
+
+type CertificateAuthTestSuite struct {
+	*grpc_testing.InterceptorTestSuite
+}
+
+func (s *CertificateAuthTestSuite) TestUnary_NoCertificate() {
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+	assert.Equal(s.T(), codes.Unauthenticated, status.Code(err), "calls without a client certificate must be rejected")
+}
+
+func (s *CertificateAuthTestSuite) TestUnary_AllowedIdentities() {
+	for _, tcase := range []struct {
+		template *x509.Certificate
+		identity string
+	}{
+		{&x509.Certificate{URIs: []*url.URL{mustParseURL("spiffe://example.org/billing")}}, "spiffe://example.org/billing"},
+		{&x509.Certificate{Subject: pkix.Name{CommonName: "admin"}}, "admin"},
+		{&x509.Certificate{Subject: pkix.Name{CommonName: "ops"}, DNSNames: []string{"ops.example.com"}}, "ops"},
+	} {
+		client := s.NewClientWithCert(s.NewClientCert(tcase.template))
+		pong, err := client.Ping(s.SimpleCtx(), goodPing)
+		require.NoError(s.T(), err, "%s must be allowed", tcase.identity)
+		assert.Equal(s.T(), tcase.identity, pong.Value, "identity must be passed to the handler")
+	}
+}
+
+func (s *CertificateAuthTestSuite) TestUnary_DeniedIdentities() {
+	for _, template := range []*x509.Certificate{
+		{URIs: []*url.URL{mustParseURL("spiffe://other.org/billing")}},
+		{Subject: pkix.Name{CommonName: "guest"}, DNSNames: []string{"guest.example.com"}},
+	} {
+		client := s.NewClientWithCert(s.NewClientCert(template))
+		_, err := client.Ping(s.SimpleCtx(), goodPing)
+		assert.Equal(s.T(), codes.PermissionDenied, status.Code(err), "identities outside of the allowlists must be denied")
+	}
+}
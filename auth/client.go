@@ -0,0 +1,158 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rkollar/go-grpc-middleware"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TokenSourceFunc is a function that implements oauth2.TokenSource, for tokens that are not obtained through OAuth2.
+//
+// The returned token must have its AccessToken set. TokenType defaults to `Bearer`, and a zero Expiry means
+// that the token never expires.
+type TokenSourceFunc func() (*oauth2.Token, error)
+
+// Token calls f().
+func (f TokenSourceFunc) Token() (*oauth2.Token, error) {
+	return f()
+}
+
+// UnaryClientInterceptor returns a new unary client interceptor that sets the `authorization` header of
+// outgoing calls to a token obtained from the source.
+//
+// Tokens are cached and refreshed ahead of their expiry. If a call fails with `Unauthenticated`, the
+// cached token is refreshed and the call is retried once with the new token. For this to work, the
+// source must return a new token on every call, so do not wrap it in oauth2.ReuseTokenSource.
+func UnaryClientInterceptor(source oauth2.TokenSource, opts ...ClientOption) grpc.UnaryClientInterceptor {
+	return unaryClientInterceptor(newTokenCache(source, evaluateClientOptions(opts)))
+}
+
+func unaryClientInterceptor(cache *tokenCache) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		token, err := cache.get()
+		if err != nil {
+			return err
+		}
+		err = invoker(outgoingWithToken(ctx, token), method, req, reply, cc, opts...)
+		if status.Code(err) != codes.Unauthenticated {
+			return err
+		}
+		fresh, refreshErr := cache.refresh(token)
+		if refreshErr != nil || fresh.AccessToken == token.AccessToken {
+			return err
+		}
+		return invoker(outgoingWithToken(ctx, fresh), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor returns a new streaming client interceptor that sets the `authorization` header
+// of outgoing streams to a token obtained from the source.
+//
+// Tokens are cached and refreshed ahead of their expiry. If establishing the stream fails with
+// `Unauthenticated`, the cached token is refreshed and the stream is retried once with the new token.
+// Streams failing with `Unauthenticated` later on are not retried, but their token is refreshed for the
+// next call.
+func StreamClientInterceptor(source oauth2.TokenSource, opts ...ClientOption) grpc.StreamClientInterceptor {
+	return streamClientInterceptor(newTokenCache(source, evaluateClientOptions(opts)))
+}
+
+func streamClientInterceptor(cache *tokenCache) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		token, err := cache.get()
+		if err != nil {
+			return nil, err
+		}
+		stream, err := streamer(outgoingWithToken(ctx, token), desc, cc, method, opts...)
+		if status.Code(err) == codes.Unauthenticated {
+			fresh, refreshErr := cache.refresh(token)
+			if refreshErr == nil && fresh.AccessToken != token.AccessToken {
+				token = fresh
+				stream, err = streamer(outgoingWithToken(ctx, token), desc, cc, method, opts...)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		wrapped := grpc_middleware.WrapClientStream(stream)
+		wrapped.OnRecvMsg = func(_ interface{}, err error) {
+			if status.Code(err) == codes.Unauthenticated {
+				cache.invalidate(token)
+			}
+		}
+		return wrapped, nil
+	}
+}
+
+// ClientMiddleware returns a new grpc_middleware.Middleware that authenticates outgoing calls with tokens
+// obtained from the source. The unary and stream interceptors share the cached token.
+func ClientMiddleware(source oauth2.TokenSource, opts ...ClientOption) grpc_middleware.Middleware {
+	cache := newTokenCache(source, evaluateClientOptions(opts))
+	return grpc_middleware.Middleware{
+		UnaryClient:  unaryClientInterceptor(cache),
+		StreamClient: streamClientInterceptor(cache),
+	}
+}
+
+func outgoingWithToken(ctx context.Context, token *oauth2.Token) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	md.Set(headerAuthorize, token.Type()+" "+token.AccessToken)
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// tokenCache caches the token of a source until shortly before it expires.
+type tokenCache struct {
+	source        oauth2.TokenSource
+	refreshBefore time.Duration
+
+	mu    sync.Mutex
+	token *oauth2.Token
+}
+
+func newTokenCache(source oauth2.TokenSource, o *clientOptions) *tokenCache {
+	return &tokenCache{source: source, refreshBefore: o.refreshBefore}
+}
+
+// get returns the cached token, fetching a new one from the source if it is missing or about to expire.
+func (c *tokenCache) get() (*oauth2.Token, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != nil && (c.token.Expiry.IsZero() || time.Now().Add(c.refreshBefore).Before(c.token.Expiry)) {
+		return c.token, nil
+	}
+	token, err := c.source.Token()
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "grpc_auth: failed to obtain token: %v", err)
+	}
+	if token == nil || token.AccessToken == "" {
+		return nil, status.Errorf(codes.Unauthenticated, "grpc_auth: token source returned an empty token")
+	}
+	c.token = token
+	return token, nil
+}
+
+// invalidate drops the cached token if it is still the rejected one, so that concurrent calls rejected
+// with the same token cause a single refresh.
+func (c *tokenCache) invalidate(rejected *oauth2.Token) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token == rejected {
+		c.token = nil
+	}
+}
+
+// refresh invalidates the rejected token and returns a fresh one.
+func (c *tokenCache) refresh(rejected *oauth2.Token) (*oauth2.Token, error) {
+	c.invalidate(rejected)
+	return c.get()
+}
@@ -0,0 +1,165 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_auth_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const pingMethod = "/mwitkow.testproto.TestService/Ping"
+
+// countingTokenSource returns a new token on every call, valid for the given lifetime.
+type countingTokenSource struct {
+	lifetime time.Duration
+	calls    int
+}
+
+func (ts *countingTokenSource) Token() (*oauth2.Token, error) {
+	ts.calls++
+	return &oauth2.Token{
+		AccessToken: fmt.Sprintf("token-%d", ts.calls),
+		Expiry:      time.Now().Add(ts.lifetime),
+	}, nil
+}
+
+func outgoingAuthorization(ctx context.Context) []string {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	return md.Get("authorization")
+}
+
+// rejectingInvoker records the authorization of calls and rejects the given tokens.
+func rejectingInvoker(seen *[]string, rejected ...string) grpc.UnaryInvoker {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		auth := outgoingAuthorization(ctx)
+		*seen = append(*seen, auth...)
+		for _, r := range rejected {
+			if len(auth) == 1 && auth[0] == "Bearer "+r {
+				return status.Errorf(codes.Unauthenticated, "token %s rejected", r)
+			}
+		}
+		return nil
+	}
+}
+
+func TestUnaryClientInterceptor_CachesToken(t *testing.T) {
+	source := &countingTokenSource{lifetime: time.Hour}
+	interceptor := grpc_auth.UnaryClientInterceptor(source)
+	var seen []string
+	ctx := metadata.AppendToOutgoingContext(context.TODO(), "authorization", "Bearer stale", "other", "value")
+	for i := 0; i < 3; i++ {
+		require.NoError(t, interceptor(ctx, pingMethod, nil, nil, nil, rejectingInvoker(&seen)))
+	}
+	assert.Equal(t, []string{"Bearer token-1", "Bearer token-1", "Bearer token-1"}, seen, "cached token must replace the authorization header")
+	assert.Equal(t, 1, source.calls, "token must be fetched once while it is valid")
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	assert.Equal(t, []string{"Bearer stale"}, md.Get("authorization"), "caller's metadata must not be modified")
+}
+
+func TestUnaryClientInterceptor_RefreshesBeforeExpiry(t *testing.T) {
+	source := &countingTokenSource{lifetime: 30 * time.Second}
+	var seen []string
+	interceptor := grpc_auth.UnaryClientInterceptor(source)
+	require.NoError(t, interceptor(context.TODO(), pingMethod, nil, nil, nil, rejectingInvoker(&seen)))
+	require.NoError(t, interceptor(context.TODO(), pingMethod, nil, nil, nil, rejectingInvoker(&seen)))
+	assert.Equal(t, 2, source.calls, "tokens expiring within the refresh window must be refreshed")
+
+	interceptor = grpc_auth.UnaryClientInterceptor(source, grpc_auth.WithRefreshBefore(10*time.Second))
+	require.NoError(t, interceptor(context.TODO(), pingMethod, nil, nil, nil, rejectingInvoker(&seen)))
+	require.NoError(t, interceptor(context.TODO(), pingMethod, nil, nil, nil, rejectingInvoker(&seen)))
+	assert.Equal(t, 3, source.calls, "tokens outside of the refresh window must be cached")
+}
+
+func TestUnaryClientInterceptor_RetriesOnceOnUnauthenticated(t *testing.T) {
+	source := &countingTokenSource{lifetime: time.Hour}
+	interceptor := grpc_auth.UnaryClientInterceptor(source)
+	var seen []string
+	require.NoError(t, interceptor(context.TODO(), pingMethod, nil, nil, nil, rejectingInvoker(&seen, "token-1")))
+	assert.Equal(t, []string{"Bearer token-1", "Bearer token-2"}, seen, "call must be retried with a refreshed token")
+
+	seen = nil
+	err := interceptor(context.TODO(), pingMethod, nil, nil, nil, rejectingInvoker(&seen, "token-2", "token-3"))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "error of the retry must be returned")
+	assert.Equal(t, []string{"Bearer token-2", "Bearer token-3"}, seen, "call must be retried only once")
+}
+
+func TestUnaryClientInterceptor_NoRetryWithSameToken(t *testing.T) {
+	source := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "static", TokenType: "bearer"})
+	interceptor := grpc_auth.UnaryClientInterceptor(source)
+	var seen []string
+	err := interceptor(context.TODO(), pingMethod, nil, nil, nil, rejectingInvoker(&seen, "static"))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Equal(t, []string{"Bearer static"}, seen, "call must not be retried with the rejected token")
+}
+
+func TestUnaryClientInterceptor_SourceError(t *testing.T) {
+	source := grpc_auth.TokenSourceFunc(func() (*oauth2.Token, error) {
+		return nil, errors.New("identity provider down")
+	})
+	interceptor := grpc_auth.UnaryClientInterceptor(source)
+	var seen []string
+	err := interceptor(context.TODO(), pingMethod, nil, nil, nil, rejectingInvoker(&seen))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "source errors must fail the call")
+	assert.Contains(t, err.Error(), "identity provider down", "source errors must be reported")
+	assert.Empty(t, seen, "call must not be invoked without a token")
+}
+
+type unauthenticatedClientStream struct {
+	grpc.ClientStream
+}
+
+func (s *unauthenticatedClientStream) RecvMsg(m interface{}) error {
+	return status.Errorf(codes.Unauthenticated, "token expired")
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	source := &countingTokenSource{lifetime: time.Hour}
+	interceptor := grpc_auth.StreamClientInterceptor(source)
+	var seen []string
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		auth := outgoingAuthorization(ctx)
+		seen = append(seen, auth...)
+		if auth[0] == "Bearer token-1" {
+			return nil, status.Errorf(codes.Unauthenticated, "token rejected")
+		}
+		return &unauthenticatedClientStream{}, nil
+	}
+
+	stream, err := interceptor(context.TODO(), &grpc.StreamDesc{}, nil, pingMethod, streamer)
+	require.NoError(t, err, "stream must be retried with a refreshed token")
+	assert.Equal(t, []string{"Bearer token-1", "Bearer token-2"}, seen)
+
+	err = stream.RecvMsg(nil)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "stream errors must be returned")
+	_, err = interceptor(context.TODO(), &grpc.StreamDesc{}, nil, pingMethod, streamer)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bearer token-1", "Bearer token-2", "Bearer token-3"}, seen, "token rejected by a stream must be refreshed for the next call")
+}
+
+func TestClientMiddleware_SharesToken(t *testing.T) {
+	source := &countingTokenSource{lifetime: time.Hour}
+	mw := grpc_auth.ClientMiddleware(source)
+	var seen []string
+	require.NoError(t, mw.UnaryClient(context.TODO(), pingMethod, nil, nil, nil, rejectingInvoker(&seen)))
+	_, err := mw.StreamClient(context.TODO(), &grpc.StreamDesc{}, nil, pingMethod, func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		seen = append(seen, outgoingAuthorization(ctx)...)
+		return &unauthenticatedClientStream{}, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bearer token-1", "Bearer token-1"}, seen, "unary and stream interceptors must share the token")
+	assert.Nil(t, mw.UnaryServer, "client middleware must not set server interceptors")
+}
@@ -0,0 +1,30 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_auth
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AnyOf returns an AuthFunc that tries the AuthFuncs in order and accepts the first one that succeeds, e.g.
+// to accept mTLS, then bearer tokens, then API keys.
+//
+// If all of them fail, the call fails with `Unauthenticated` and a message listing the failure of each.
+func AnyOf(authFuncs ...AuthFunc) AuthFunc {
+	return func(ctx context.Context) (context.Context, error) {
+		reasons := make([]string, 0, len(authFuncs))
+		for _, authFunc := range authFuncs {
+			newCtx, err := authFunc(ctx)
+			if err == nil {
+				return newCtx, nil
+			}
+			reasons = append(reasons, status.Convert(err).Message())
+		}
+		return nil, status.Errorf(codes.Unauthenticated, "no authentication method succeeded: %s", strings.Join(reasons, "; "))
+	}
+}
@@ -2,7 +2,7 @@
 // See LICENSE for licensing terms.
 
 /*
-`grpc_auth` a generic server-side auth middleware for gRPC.
+`grpc_auth` a generic auth middleware for gRPC.
 
 Server Side Auth Middleware
 
@@ -13,7 +13,31 @@ The middleware takes a user-customizable `AuthFunc`, which can be customized to
 auth information from the request. The extracted information can be put in the `context.Context` of
 handlers downstream for retrieval.
 
-It also allows for per-service implementation overrides of `AuthFunc`. See `ServiceAuthFuncOverride`.
+For transport-based credentials, `CertificateAuthFunc` authenticates peers by their verified TLS client
+certificate. It extracts the common name, DNS and URI SANs and the SPIFFE ID of the certificate, checks
+them against allowlists and SPIFFE trust domains, and makes the identity available to handlers through
+`PeerIdentityFromContext`.
+
+It also allows for per-service implementation overrides of `AuthFunc`, see `ServiceAuthFuncOverride`, and
+per-method overrides, see `WithMethodOverrides`. `AnyOf` combines several `AuthFunc`s, accepting calls
+that any of them authenticates.
+
+`AuthFunc`s that are expensive to call, e.g. because they introspect tokens remotely, can be wrapped with
+`CachingAuthFunc`. It caches their results by the credential of the call in a bounded LRU cache, until
+the TTL passes or the token expires, optionally caches failures too, and shares a single lookup between
+concurrent calls with the same credential.
+
+Streams are only authenticated when they are opened, and long-lived ones can outlive the credentials they
+were opened with. AuthFuncs store the expiry of credentials with `ContextWithExpiry`, and with
+`WithStreamExpiry` the interceptors terminate streams with `Unauthenticated` once it passes.
+`WithStreamRefresh` additionally lets callers extend the expiry by sending refreshed credentials in-band.
+
+Client Side Auth Middleware
+
+On the client side, `UnaryClientInterceptor` and `StreamClientInterceptor` set the `:authorization`
+header of outgoing calls to tokens obtained from an `oauth2.TokenSource` (or any other source through
+`TokenSourceFunc`). Tokens are cached and refreshed ahead of their expiry, and calls rejected with
+`Unauthenticated` are retried once with a freshly obtained token.
 
 Please see examples for simple examples of use.
 */
@@ -0,0 +1,144 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_auth
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type expiryMarker struct{}
+
+var expiryMarkerKey = &expiryMarker{}
+
+// ContextWithExpiry returns a copy of the context carrying the time at which the credentials of the call expire.
+//
+// AuthFuncs store the expiry of the credentials they authenticate, e.g. of a bearer token, so that streams
+// outliving them can be terminated, see WithStreamExpiry.
+func ContextWithExpiry(ctx context.Context, expiresAt time.Time) context.Context {
+	return context.WithValue(ctx, expiryMarkerKey, expiresAt)
+}
+
+// ExpiryFromContext returns the expiry of the credentials stored in the context by an AuthFunc.
+func ExpiryFromContext(ctx context.Context) (time.Time, bool) {
+	expiresAt, ok := ctx.Value(expiryMarkerKey).(time.Time)
+	return expiresAt, ok && !expiresAt.IsZero()
+}
+
+// StreamRefreshFunc inspects each message received on a stream for refreshed credentials sent in-band, e.g.
+// a token in a dedicated field of the message.
+//
+// If the message carries credentials, it authenticates them and returns their expiry, which replaces the
+// expiry of the stream. Implementations must check that the credentials belong to the same caller as the
+// stream, whose context is passed in. Messages without credentials return a zero time. If an error is
+// returned, it is returned from the stream's RecvMsg.
+type StreamRefreshFunc func(ctx context.Context, msg interface{}) (expiresAt time.Time, err error)
+
+var errCredentialsExpired = status.Errorf(codes.Unauthenticated, "Credentials expired")
+
+// serveUntilExpiry runs the stream handler, terminating the stream once the credentials of the stream expire.
+//
+// The handler runs on the goroutine of the interceptor, so that it never outlives the stream. On expiry
+// the context of the stream is cancelled and all further calls on the stream fail, so that handlers
+// return as soon as they next use either. Handlers blocked in RecvMsg cannot be interrupted, and only
+// return once the next message arrives or the client goes away.
+func (o *options) serveUntilExpiry(srv interface{}, stream grpc.ServerStream, handler grpc.StreamHandler, ctx context.Context, expiresAt time.Time) error {
+	if !time.Now().Before(expiresAt) {
+		return errCredentialsExpired
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s := &expiringServerStream{ServerStream: stream, ctx: ctx, cancel: cancel, refresh: o.streamRefresh, expiresAt: expiresAt}
+	s.mu.Lock()
+	s.timer = time.AfterFunc(time.Until(expiresAt), s.expire)
+	s.mu.Unlock()
+	defer s.timer.Stop()
+
+	err := handler(srv, s)
+	if s.isExpired() {
+		return errCredentialsExpired
+	}
+	return err
+}
+
+// expiringServerStream is a grpc.ServerStream that fails once its credentials expire.
+type expiringServerStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	cancel  context.CancelFunc
+	refresh StreamRefreshFunc
+
+	mu        sync.Mutex
+	timer     *time.Timer
+	expiresAt time.Time
+	expired   int32
+}
+
+func (s *expiringServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *expiringServerStream) SendMsg(m interface{}) error {
+	if s.isExpired() {
+		return errCredentialsExpired
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+func (s *expiringServerStream) RecvMsg(m interface{}) error {
+	if s.isExpired() {
+		return errCredentialsExpired
+	}
+	err := s.ServerStream.RecvMsg(m)
+	// The credentials may have expired while blocked, in which case the message is not accepted.
+	if s.isExpired() {
+		return errCredentialsExpired
+	}
+	if err != nil {
+		return err
+	}
+	if s.refresh == nil {
+		return nil
+	}
+	expiresAt, err := s.refresh(s.ctx, m)
+	if err != nil {
+		return err
+	}
+	if !expiresAt.IsZero() {
+		s.extend(expiresAt)
+	}
+	return nil
+}
+
+func (s *expiringServerStream) isExpired() bool {
+	return atomic.LoadInt32(&s.expired) == 1
+}
+
+// expire fails the stream, unless its expiry was extended after the timer fired.
+func (s *expiringServerStream) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Now().Before(s.expiresAt) {
+		return
+	}
+	if atomic.CompareAndSwapInt32(&s.expired, 0, 1) {
+		s.cancel()
+	}
+}
+
+// extend replaces the expiry of the stream, unless it has already expired.
+func (s *expiringServerStream) extend(expiresAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.isExpired() {
+		s.expiresAt = expiresAt
+		s.timer.Reset(time.Until(expiresAt))
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiringServerStream_ExtendRacingExpire(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	s := &expiringServerStream{ctx: ctx, cancel: cancel, expiresAt: time.Now()}
+	s.timer = time.AfterFunc(time.Hour, s.expire)
+	defer s.timer.Stop()
+
+	// The timer has fired, but its callback only runs once the credentials have been refreshed.
+	s.extend(time.Now().Add(time.Hour))
+	s.expire()
+	assert.False(t, s.isExpired(), "streams must not expire by a timer that fired before they were extended")
+	assert.NoError(t, ctx.Err())
+
+	s.extend(time.Now().Add(-time.Second))
+	s.expire()
+	assert.True(t, s.isExpired(), "streams must expire once their extended expiry passes")
+	assert.Error(t, ctx.Err())
+}
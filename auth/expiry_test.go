@@ -0,0 +1,180 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_auth_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// channelServerStream is a grpc.ServerStream receiving the messages sent on its channel, blocking until then.
+type channelServerStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	msgs chan string
+}
+
+func (s *channelServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *channelServerStream) SendMsg(m interface{}) error {
+	return nil
+}
+
+func (s *channelServerStream) RecvMsg(m interface{}) error {
+	select {
+	case msg, ok := <-s.msgs:
+		if !ok {
+			return io.EOF
+		}
+		*(m.(*string)) = msg
+		return nil
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+func expiringAuthFunc(expiresAt time.Time) grpc_auth.AuthFunc {
+	return func(ctx context.Context) (context.Context, error) {
+		return grpc_auth.ContextWithExpiry(ctx, expiresAt), nil
+	}
+}
+
+// recvAll is a stream handler receiving messages until the stream fails.
+func recvAll(srv interface{}, stream grpc.ServerStream) error {
+	for {
+		var msg string
+		if err := stream.RecvMsg(&msg); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func TestExpiryFromContext(t *testing.T) {
+	_, ok := grpc_auth.ExpiryFromContext(context.TODO())
+	assert.False(t, ok, "contexts without expiry must not have one")
+	expiresAt := time.Now().Add(time.Hour)
+	got, ok := grpc_auth.ExpiryFromContext(grpc_auth.ContextWithExpiry(context.TODO(), expiresAt))
+	assert.True(t, ok)
+	assert.Equal(t, expiresAt, got)
+}
+
+func TestStreamServerInterceptor_Expiry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	interceptor := grpc_auth.StreamServerInterceptor(expiringAuthFunc(time.Now().Add(50*time.Millisecond)), grpc_auth.WithStreamExpiry())
+
+	start := time.Now()
+	handlerDone := false
+	err := interceptor(nil, &channelServerStream{ctx: ctx, msgs: make(chan string)}, &grpc.StreamServerInfo{FullMethod: pingMethod}, func(srv interface{}, stream grpc.ServerStream) error {
+		defer func() { handlerDone = true }()
+		<-stream.Context().Done()
+		assert.Equal(t, codes.Unauthenticated, status.Code(stream.SendMsg("late")), "sending on expired streams must fail")
+		return stream.Context().Err()
+	})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "streams must be terminated on expiry")
+	assert.True(t, time.Since(start) < 5*time.Second, "context of streams must be cancelled on expiry")
+	assert.True(t, handlerDone, "handlers must have returned when the interceptor returns")
+
+	interceptor = grpc_auth.StreamServerInterceptor(expiringAuthFunc(time.Now().Add(50*time.Millisecond)), grpc_auth.WithStreamExpiry())
+	msgs := make(chan string, 1)
+	time.AfterFunc(100*time.Millisecond, func() { msgs <- "late" })
+	var recvErr error
+	err = interceptor(nil, &channelServerStream{ctx: ctx, msgs: msgs}, &grpc.StreamServerInfo{FullMethod: pingMethod}, func(srv interface{}, stream grpc.ServerStream) error {
+		recvErr = recvAll(srv, stream)
+		return recvErr
+	})
+	assert.Equal(t, codes.Unauthenticated, status.Code(recvErr), "messages received after expiry must be rejected")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "streams blocked in RecvMsg must be terminated on their next message")
+
+	called := false
+	err = grpc_auth.StreamServerInterceptor(expiringAuthFunc(time.Now().Add(-time.Second)), grpc_auth.WithStreamExpiry())(nil, &channelServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: pingMethod}, func(srv interface{}, stream grpc.ServerStream) error {
+		called = true
+		return nil
+	})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "streams with expired credentials must be rejected")
+	assert.False(t, called, "handlers must not be called for expired credentials")
+
+	msgs = make(chan string)
+	close(msgs)
+	err = grpc_auth.StreamServerInterceptor(expiringAuthFunc(time.Now().Add(-time.Second)))(nil, &channelServerStream{ctx: ctx, msgs: msgs}, &grpc.StreamServerInfo{FullMethod: pingMethod}, recvAll)
+	assert.NoError(t, err, "expiry must not be enforced without the option")
+}
+
+func TestStreamServerInterceptor_Refresh(t *testing.T) {
+	refresh := func(ctx context.Context, msg interface{}) (time.Time, error) {
+		switch *(msg.(*string)) {
+		case "refresh":
+			return time.Now().Add(100 * time.Millisecond), nil
+		case "stolen":
+			return time.Time{}, status.Errorf(codes.PermissionDenied, "credentials of another caller")
+		}
+		return time.Time{}, nil
+	}
+	run := func(msgs ...string) error {
+		stream := &channelServerStream{ctx: context.TODO(), msgs: make(chan string)}
+		go func() {
+			defer close(stream.msgs)
+			for _, m := range msgs {
+				time.Sleep(40 * time.Millisecond)
+				select {
+				case stream.msgs <- m:
+				case <-time.After(time.Second):
+					return
+				}
+			}
+		}()
+		interceptor := grpc_auth.StreamServerInterceptor(expiringAuthFunc(time.Now().Add(100*time.Millisecond)), grpc_auth.WithStreamRefresh(refresh))
+		return interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: pingMethod}, recvAll)
+	}
+
+	assert.NoError(t, run("refresh", "refresh", "refresh", "refresh", "refresh"), "refreshed streams must outlive the original expiry")
+	assert.Equal(t, codes.Unauthenticated, status.Code(run("data", "data", "data", "data", "data")), "streams without refreshed credentials must expire")
+	assert.Equal(t, codes.PermissionDenied, status.Code(run("stolen")), "errors of the refresh func must fail the stream")
+}
+
+func TestStreamServerInterceptor_ExpiryPanics(t *testing.T) {
+	interceptor := grpc_auth.StreamServerInterceptor(expiringAuthFunc(time.Now().Add(time.Hour)), grpc_auth.WithStreamExpiry())
+	assert.PanicsWithValue(t, "handler panic", func() {
+		interceptor(nil, &channelServerStream{ctx: context.TODO()}, &grpc.StreamServerInfo{FullMethod: pingMethod}, func(srv interface{}, stream grpc.ServerStream) error {
+			panic("handler panic")
+		})
+	}, "panics of handlers must be propagated to the interceptor")
+
+	expiring := grpc_auth.StreamServerInterceptor(expiringAuthFunc(time.Now().Add(10*time.Millisecond)), grpc_auth.WithStreamExpiry())
+	assert.PanicsWithValue(t, "late panic", func() {
+		expiring(nil, &channelServerStream{ctx: context.TODO()}, &grpc.StreamServerInfo{FullMethod: pingMethod}, func(srv interface{}, stream grpc.ServerStream) error {
+			<-stream.Context().Done()
+			panic("late panic")
+		})
+	}, "panics of handlers after expiry must be propagated to the interceptor")
+
+	err := interceptor(nil, &channelServerStream{ctx: context.TODO()}, &grpc.StreamServerInfo{FullMethod: pingMethod}, func(srv interface{}, stream grpc.ServerStream) error {
+		return errors.New("handler error")
+	})
+	require.Error(t, err)
+	assert.Equal(t, "handler error", err.Error(), "errors of handlers must be returned before expiry")
+}
+
+func TestUnaryServerInterceptor_Expiry(t *testing.T) {
+	interceptor := grpc_auth.UnaryServerInterceptor(expiringAuthFunc(time.Now().Add(-time.Second)), grpc_auth.WithStreamExpiry())
+	_, err := interceptor(context.TODO(), nil, &grpc.UnaryServerInfo{FullMethod: pingMethod}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "calls with expired credentials must be rejected")
+}
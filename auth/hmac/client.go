@@ -0,0 +1,155 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_hmac
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rkollar/go-grpc-middleware"
+	"github.com/rkollar/go-grpc-middleware/util/metautils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const nonceBytes = 16
+
+// UnaryClientInterceptor returns a new unary client interceptor that signs calls and their requests with the key.
+func UnaryClientInterceptor(keyID string, key []byte, opts ...ClientOption) grpc.UnaryClientInterceptor {
+	o := evaluateClientOptions(opts)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		body, err := marshalMessage(req)
+		if err != nil {
+			return status.Errorf(codes.Internal, "grpc_hmac: failed to sign request: %v", err)
+		}
+		newCtx, err := o.sign(ctx, keyID, key, method, body)
+		if err != nil {
+			return err
+		}
+		return invoker(newCtx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor returns a new streaming client interceptor that signs calls with the key.
+//
+// The request of server streaming (1:n) calls is signed too, so their stream is only established once the
+// request is sent. The messages of client streaming and bidi streaming calls are not signed.
+func StreamClientInterceptor(keyID string, key []byte, opts ...ClientOption) grpc.StreamClientInterceptor {
+	o := evaluateClientOptions(opts)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if !desc.ClientStreams {
+			return &signingClientStream{ctx: ctx, establish: func(body []byte) (grpc.ClientStream, error) {
+				newCtx, err := o.sign(ctx, keyID, key, method, body)
+				if err != nil {
+					return nil, err
+				}
+				return streamer(newCtx, desc, cc, method, opts...)
+			}}, nil
+		}
+		newCtx, err := o.sign(ctx, keyID, key, method, nil)
+		if err != nil {
+			return nil, err
+		}
+		return streamer(newCtx, desc, cc, method, opts...)
+	}
+}
+
+// signingClientStream is a grpc.ClientStream of a server streaming call, which is established with the signature of
+// its request once the request is sent.
+type signingClientStream struct {
+	grpc.ClientStream
+	ctx       context.Context
+	establish func(body []byte) (grpc.ClientStream, error)
+}
+
+var errRequestNotSent = status.Errorf(codes.Internal, "grpc_hmac: request of the stream must be sent first")
+
+func (s *signingClientStream) Context() context.Context {
+	if s.ClientStream == nil {
+		return s.ctx
+	}
+	return s.ClientStream.Context()
+}
+
+func (s *signingClientStream) SendMsg(m interface{}) error {
+	if s.ClientStream != nil {
+		return s.ClientStream.SendMsg(m)
+	}
+	body, err := marshalMessage(m)
+	if err != nil {
+		return status.Errorf(codes.Internal, "grpc_hmac: failed to sign request: %v", err)
+	}
+	stream, err := s.establish(body)
+	if err != nil {
+		return err
+	}
+	s.ClientStream = stream
+	return stream.SendMsg(m)
+}
+
+func (s *signingClientStream) RecvMsg(m interface{}) error {
+	if s.ClientStream == nil {
+		return errRequestNotSent
+	}
+	return s.ClientStream.RecvMsg(m)
+}
+
+func (s *signingClientStream) CloseSend() error {
+	if s.ClientStream == nil {
+		return errRequestNotSent
+	}
+	return s.ClientStream.CloseSend()
+}
+
+func (s *signingClientStream) Header() (metadata.MD, error) {
+	if s.ClientStream == nil {
+		return nil, errRequestNotSent
+	}
+	return s.ClientStream.Header()
+}
+
+func (s *signingClientStream) Trailer() metadata.MD {
+	if s.ClientStream == nil {
+		return nil
+	}
+	return s.ClientStream.Trailer()
+}
+
+// ClientMiddleware returns a new grpc_middleware.Middleware that signs calls with the key on the client side.
+func ClientMiddleware(keyID string, key []byte, opts ...ClientOption) grpc_middleware.Middleware {
+	return grpc_middleware.Middleware{
+		UnaryClient:  UnaryClientInterceptor(keyID, key, opts...),
+		StreamClient: StreamClientInterceptor(keyID, key, opts...),
+	}
+}
+
+// sign adds the signature of the call to the outgoing metadata.
+func (o *clientOptions) sign(ctx context.Context, keyID string, key []byte, method string, body []byte) (context.Context, error) {
+	nonce := make([]byte, nonceBytes)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, status.Errorf(codes.Internal, "grpc_hmac: failed to generate nonce: %v", err)
+	}
+	md := metautils.ExtractOutgoing(ctx).Clone()
+	call := &signedCall{
+		fullMethod:  method,
+		timestamp:   strconv.FormatInt(time.Now().Unix(), 10),
+		nonce:       hex.EncodeToString(nonce),
+		headerNames: o.signedHeaders,
+		md:          metadata.MD(md),
+		body:        body,
+	}
+	md.Set(headerKeyID, keyID)
+	md.Set(headerTimestamp, call.timestamp)
+	md.Set(headerNonce, call.nonce)
+	md.Set(headerSignedHeaders, strings.Join(call.headerNames, ","))
+	md.Set(headerSignature, base64.StdEncoding.EncodeToString(call.sign(key)))
+	return md.ToOutgoing(ctx), nil
+}
@@ -0,0 +1,27 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`grpc_hmac` provides HMAC request signing for service-to-service calls crossing untrusted proxies.
+
+Request Signing
+
+On the client side, `UnaryClientInterceptor` and `StreamClientInterceptor` sign each call with a shared key
+identified by its key ID. The HMAC-SHA256 signature covers the method name, selected metadata headers, a
+timestamp, a random nonce and, for unary and server streaming calls, the serialized request. It is sent in `x-hmac-*` metadata
+headers along with the key ID, timestamp, nonce and the names of the signed headers. The signing
+interceptors should run last in the client chain, so that they sign the metadata as it is sent.
+
+On the server side, `AuthFunc` verifies the signature with the key looked up in a `KeyStore`, rejects
+timestamps outside of the allowed clock skew, and rejects replayed nonces through a `NonceCache`. As a
+`grpc_auth.AuthFunc` only sees the context of the call, the `Middleware` of this package must run before
+`grpc_auth` to make the method and the request available to it.
+
+The request of server streaming calls is signed too: their stream is only established once the request is
+sent, and its signature is verified once the handler receives it. Only then is the call authenticated: its
+nonce is recorded and its `grpc_auth.Principal` becomes available in the context. Messages of client
+streaming and bidi streaming calls are not signed, as they are sent after the call is authenticated.
+
+Please see examples for simple examples of use.
+*/
+package grpc_hmac
@@ -0,0 +1,27 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_hmac_test
+
+import (
+	"github.com/rkollar/go-grpc-middleware"
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"github.com/rkollar/go-grpc-middleware/auth/hmac"
+	"google.golang.org/grpc"
+)
+
+// Simple example of server initialization code, verifying signed requests before authenticating them.
+func Example_serverConfig() {
+	keys := grpc_hmac.StaticKeys{"billing": []byte("shared secret of billing")}
+	_ = grpc.NewServer(grpc_middleware.ServerOptions(
+		grpc_hmac.Middleware(),
+		grpc_auth.Middleware(grpc_hmac.AuthFunc(keys, grpc_hmac.WithRequiredHeaders("x-request-id"))),
+	)...)
+}
+
+// Simple example of client initialization code, signing requests with the key of the billing service.
+func Example_clientConfig() {
+	_, _ = grpc.Dial("payments:443", grpc_middleware.DialOptions(
+		grpc_hmac.ClientMiddleware("billing", []byte("shared secret of billing"), grpc_hmac.WithSignedHeaders("x-request-id")),
+	)...)
+}
@@ -0,0 +1,258 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_hmac
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"github.com/rkollar/go-grpc-middleware/tags"
+	pb_testproto "github.com/rkollar/go-grpc-middleware/testing/testproto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	pingMethod     = "/mwitkow.testproto.TestService/Ping"
+	pingListMethod = "/mwitkow.testproto.TestService/PingList"
+)
+
+var testKeys = StaticKeys{"billing": []byte("billing-secret")}
+
+// signedMD signs the request with the client interceptor, returning the metadata it sends.
+func signedMD(t *testing.T, ctx context.Context, keyID string, req interface{}, opts ...ClientOption) metadata.MD {
+	var md metadata.MD
+	err := UnaryClientInterceptor(keyID, testKeys[keyID], opts...)(ctx, pingMethod, req, nil, nil, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	})
+	require.NoError(t, err)
+	return md
+}
+
+// serve verifies the request with the AuthFunc behind the server interceptor, as grpc_auth would.
+func serve(authFunc grpc_auth.AuthFunc, md metadata.MD, fullMethod string, req interface{}) (context.Context, error) {
+	ctx := grpc_ctxtags.SetInContext(metadata.NewIncomingContext(context.TODO(), md), grpc_ctxtags.NewTags())
+	resp, err := UnaryServerInterceptor()(ctx, req, &grpc.UnaryServerInfo{FullMethod: fullMethod}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return authFunc(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(context.Context), nil
+}
+
+func TestAuthFunc(t *testing.T) {
+	authFunc := AuthFunc(testKeys)
+	req := &pb_testproto.PingRequest{Value: "something"}
+	md := signedMD(t, context.TODO(), "billing", req)
+
+	ctx, err := serve(authFunc, md, pingMethod, req)
+	require.NoError(t, err, "signed requests must be accepted")
+	principal, ok := grpc_auth.PrincipalFromContext(ctx)
+	require.True(t, ok, "principal must be stored in the context")
+	assert.Equal(t, "billing", principal.Name, "principal must be named after the key ID")
+	assert.Equal(t, "billing", grpc_ctxtags.Extract(ctx).Values()["auth.hmac_key_id"], "key ID must be tagged")
+
+	_, err = serve(authFunc, md, pingMethod, req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "replayed requests must be rejected")
+	assert.Contains(t, err.Error(), "Replayed")
+
+	unknownKey := signedMD(t, context.TODO(), "billing", req)
+	unknownKey.Set(headerKeyID, "shipping")
+	for _, tcase := range []struct {
+		name       string
+		md         metadata.MD
+		fullMethod string
+		req        interface{}
+	}{
+		{"unsigned", metadata.MD{}, pingMethod, req},
+		{"tampered", signedMD(t, context.TODO(), "billing", req), pingMethod, &pb_testproto.PingRequest{Value: "tampered"}},
+		{"redirected", signedMD(t, context.TODO(), "billing", req), "/mwitkow.testproto.TestService/PingError", req},
+		{"unknown key", unknownKey, pingMethod, req},
+	} {
+		_, err := serve(authFunc, tcase.md, tcase.fullMethod, tcase.req)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), "%s requests must be rejected", tcase.name)
+	}
+
+	_, err = authFunc(metadata.NewIncomingContext(context.TODO(), signedMD(t, context.TODO(), "billing", req)))
+	assert.Equal(t, codes.Internal, status.Code(err), "calls not captured by the server interceptor must fail")
+}
+
+func TestAuthFunc_SignedHeaders(t *testing.T) {
+	authFunc := AuthFunc(testKeys, WithRequiredHeaders("X-Request-Id"))
+	req := &pb_testproto.PingRequest{Value: "something"}
+	ctx := metadata.AppendToOutgoingContext(context.TODO(), "x-request-id", "1234", "x-other", "unsigned")
+
+	md := signedMD(t, ctx, "billing", req, WithSignedHeaders("X-Request-Id", "x-hmac-signature"))
+	assert.Equal(t, []string{"x-request-id"}, md.Get(headerSignedHeaders), "signature headers must not be signed")
+	md.Set("x-other", "changed")
+	_, err := serve(authFunc, md, pingMethod, req)
+	assert.NoError(t, err, "changes to unsigned headers must be accepted")
+
+	md = signedMD(t, ctx, "billing", req, WithSignedHeaders("x-request-id"))
+	md.Set("x-request-id", "5678")
+	_, err = serve(authFunc, md, pingMethod, req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "changes to signed headers must be rejected")
+
+	md = signedMD(t, ctx, "billing", req)
+	_, err = serve(authFunc, md, pingMethod, req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "requests not signing required headers must be rejected")
+	assert.Contains(t, err.Error(), "x-request-id")
+}
+
+func TestAuthFunc_ClockSkew(t *testing.T) {
+	req := &pb_testproto.PingRequest{Value: "something"}
+	for _, tcase := range []struct {
+		offset time.Duration
+		code   codes.Code
+	}{
+		{-time.Minute, codes.OK},
+		{time.Minute, codes.OK},
+		{-3 * time.Minute, codes.Unauthenticated},
+		{3 * time.Minute, codes.Unauthenticated},
+	} {
+		authFunc := AuthFunc(testKeys, WithMaxClockSkew(2*time.Minute), WithTimeFunc(func() time.Time {
+			return time.Now().Add(tcase.offset)
+		}))
+		_, err := serve(authFunc, signedMD(t, context.TODO(), "billing", req), pingMethod, req)
+		assert.Equal(t, tcase.code, status.Code(err), "requests signed %v from the server's time", -tcase.offset)
+	}
+}
+
+type failingNonceCache struct{}
+
+func (failingNonceCache) Add(context.Context, string, string, time.Time, time.Time) (bool, error) {
+	return false, errors.New("redis down")
+}
+
+func TestAuthFunc_NonceCacheFailure(t *testing.T) {
+	req := &pb_testproto.PingRequest{Value: "something"}
+	_, err := serve(AuthFunc(testKeys, WithNonceCache(failingNonceCache{})), signedMD(t, context.TODO(), "billing", req), pingMethod, req)
+	assert.Equal(t, codes.Unavailable, status.Code(err), "nonce cache failures must fail with Unavailable")
+}
+
+func TestMemoryNonceCache(t *testing.T) {
+	cache := NewMemoryNonceCache()
+	ctx := context.TODO()
+	// The cache must only follow the clock of the AuthFunc, which is far in the past here.
+	now := time.Unix(1600000000, 0)
+	expiresAt := now.Add(time.Minute)
+
+	added, err := cache.Add(ctx, "billing", "nonce", now, expiresAt)
+	require.NoError(t, err)
+	assert.True(t, added, "new nonces must be added")
+	added, _ = cache.Add(ctx, "billing", "nonce", now, expiresAt)
+	assert.False(t, added, "remembered nonces must not be added again")
+	added, _ = cache.Add(ctx, "shipping", "nonce", now, expiresAt)
+	assert.True(t, added, "nonces must be remembered per key")
+
+	added, _ = cache.Add(ctx, "billing", "expiring", now, now.Add(-time.Second))
+	assert.True(t, added)
+	added, _ = cache.Add(ctx, "billing", "expiring", now, expiresAt)
+	assert.True(t, added, "expired nonces must be forgotten")
+	added, _ = cache.Add(ctx, "billing", "nonce", expiresAt, expiresAt)
+	assert.True(t, added, "nonces must be forgotten once they expire by the given time")
+}
+
+type recordingNonceCache struct {
+	NonceCache
+	now time.Time
+}
+
+func (c *recordingNonceCache) Add(ctx context.Context, keyID string, nonce string, now time.Time, expiresAt time.Time) (bool, error) {
+	c.now = now
+	return c.NonceCache.Add(ctx, keyID, nonce, now, expiresAt)
+}
+
+func TestAuthFunc_NonceCacheClock(t *testing.T) {
+	serverNow := time.Now().Add(-time.Minute)
+	nonces := &recordingNonceCache{NonceCache: NewMemoryNonceCache()}
+	authFunc := AuthFunc(testKeys, WithNonceCache(nonces), WithTimeFunc(func() time.Time { return serverNow }))
+	req := &pb_testproto.PingRequest{Value: "something"}
+	_, err := serve(authFunc, signedMD(t, context.TODO(), "billing", req), pingMethod, req)
+	require.NoError(t, err)
+	assert.Equal(t, serverNow, nonces.now, "nonces must be checked against the time of the AuthFunc")
+}
+
+// recordingClientStream is the grpc.ClientStream of a server streaming call, recording the metadata it was established with.
+type recordingClientStream struct {
+	grpc.ClientStream
+	md   metadata.MD
+	sent interface{}
+}
+
+func (s *recordingClientStream) SendMsg(m interface{}) error {
+	s.sent = m
+	return nil
+}
+
+// requestServerStream is the grpc.ServerStream of a server streaming call, receiving its single request.
+type requestServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+	req *pb_testproto.PingRequest
+}
+
+func (s *requestServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *requestServerStream) RecvMsg(m interface{}) error {
+	*(m.(*pb_testproto.PingRequest)) = *s.req
+	return nil
+}
+
+func TestAuthFunc_ServerStreaming(t *testing.T) {
+	desc := &grpc.StreamDesc{ServerStreams: true}
+	info := &grpc.StreamServerInfo{FullMethod: pingListMethod, IsServerStream: true}
+	signedStream := func(req *pb_testproto.PingRequest) *recordingClientStream {
+		established := &recordingClientStream{}
+		stream, err := StreamClientInterceptor("billing", testKeys["billing"])(context.TODO(), desc, nil, pingListMethod, func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			established.md, _ = metadata.FromOutgoingContext(ctx)
+			return established, nil
+		})
+		require.NoError(t, err)
+		assert.Nil(t, established.md, "stream must not be established before its request is sent")
+		require.NoError(t, stream.SendMsg(req))
+		assert.Equal(t, req, established.sent, "request must be sent on the established stream")
+		return established
+	}
+	authed := grpc_auth.StreamServerInterceptor(AuthFunc(testKeys))
+	serveStream := func(md metadata.MD, req *pb_testproto.PingRequest) error {
+		ctx := grpc_ctxtags.SetInContext(metadata.NewIncomingContext(context.TODO(), md), grpc_ctxtags.NewTags())
+		return StreamServerInterceptor()(nil, &requestServerStream{ctx: ctx, req: req}, info, func(srv interface{}, stream grpc.ServerStream) error {
+			return authed(srv, stream, info, func(srv interface{}, stream grpc.ServerStream) error {
+				_, ok := grpc_auth.PrincipalFromContext(stream.Context())
+				assert.False(t, ok, "principal must not be available before the request is verified")
+				if err := stream.RecvMsg(&pb_testproto.PingRequest{}); err != nil {
+					_, ok := grpc_auth.PrincipalFromContext(stream.Context())
+					assert.False(t, ok, "principal must not be available for requests failing verification")
+					return err
+				}
+				principal, ok := grpc_auth.PrincipalFromContext(stream.Context())
+				require.True(t, ok, "principal must be available once the request is verified")
+				assert.Equal(t, "billing", principal.Name)
+				assert.Equal(t, "billing", grpc_ctxtags.Extract(stream.Context()).Values()["auth.hmac_key_id"])
+				return nil
+			})
+		})
+	}
+	req := &pb_testproto.PingRequest{Value: "something"}
+
+	assert.NoError(t, serveStream(signedStream(req).md, req), "signed requests of server streams must be accepted")
+	md := signedStream(req).md
+	err := serveStream(md, &pb_testproto.PingRequest{Value: "tampered"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "tampered requests of server streams must be rejected")
+	assert.NoError(t, serveStream(md, req), "tampered requests must not use up the nonce of the signed one")
+	err = serveStream(md, req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "replayed requests of server streams must be rejected")
+}
@@ -0,0 +1,22 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_hmac
+
+import (
+	"context"
+)
+
+// KeyStore provides the shared keys by which requests are signed.
+type KeyStore interface {
+	// Key returns the key with the ID, or nil if there is no such key.
+	Key(ctx context.Context, keyID string) ([]byte, error)
+}
+
+// StaticKeys is a KeyStore of a fixed set of keys, keyed by key ID.
+type StaticKeys map[string][]byte
+
+// Key returns the key with the ID.
+func (s StaticKeys) Key(_ context.Context, keyID string) ([]byte, error) {
+	return s[keyID], nil
+}
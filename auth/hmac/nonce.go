@@ -0,0 +1,58 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_hmac
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// NonceCache remembers the nonces of verified requests, so that replays of them can be rejected.
+//
+// Nonces only need to be remembered until the timestamps of their requests fall out of the allowed clock
+// skew, after which replays are rejected anyway. Implementations shared between server replicas, e.g. backed
+// by Redis, reject replays across all of them.
+type NonceCache interface {
+	// Add remembers the nonce of the key until expiresAt. It returns false if the nonce is already remembered.
+	//
+	// Now is the current time of the AuthFunc, see WithTimeFunc, against which expiresAt is to be compared.
+	Add(ctx context.Context, keyID string, nonce string, now time.Time, expiresAt time.Time) (bool, error)
+}
+
+// MemoryNonceCache is a NonceCache that keeps the nonces in memory.
+type MemoryNonceCache struct {
+	mu        sync.Mutex
+	nonces    map[string]time.Time
+	nextSweep time.Time
+}
+
+// sweepInterval is how often MemoryNonceCache forgets expired nonces.
+const sweepInterval = 1 * time.Minute
+
+// NewMemoryNonceCache creates an empty MemoryNonceCache.
+func NewMemoryNonceCache() *MemoryNonceCache {
+	return &MemoryNonceCache{nonces: map[string]time.Time{}}
+}
+
+// Add remembers the nonce of the key until expiresAt.
+func (c *MemoryNonceCache) Add(_ context.Context, keyID string, nonce string, now time.Time, expiresAt time.Time) (bool, error) {
+	// Key IDs can contain any characters but newlines, which are not allowed in metadata values.
+	key := keyID + "\n" + nonce
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !now.Before(c.nextSweep) {
+		for k, exp := range c.nonces {
+			if !now.Before(exp) {
+				delete(c.nonces, k)
+			}
+		}
+		c.nextSweep = now.Add(sweepInterval)
+	}
+	if exp, ok := c.nonces[key]; ok && now.Before(exp) {
+		return false, nil
+	}
+	c.nonces[key] = expiresAt
+	return true, nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_hmac
+
+import (
+	"strings"
+	"time"
+)
+
+var (
+	defaultOptions = &options{
+		maxClockSkew: 5 * time.Minute,
+		timeFunc:     time.Now,
+	}
+)
+
+type options struct {
+	maxClockSkew    time.Duration
+	nonces          NonceCache
+	requiredHeaders []string
+	timeFunc        func() time.Time
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	for _, o := range opts {
+		o(optCopy)
+	}
+	if optCopy.nonces == nil {
+		optCopy.nonces = NewMemoryNonceCache()
+	}
+	return optCopy
+}
+
+// Option configures how AuthFunc verifies signed requests.
+type Option func(*options)
+
+// WithMaxClockSkew sets how far the timestamp of a request may be from the server's time. Defaults to five minutes.
+func WithMaxClockSkew(d time.Duration) Option {
+	return func(o *options) {
+		o.maxClockSkew = d
+	}
+}
+
+// WithNonceCache sets the cache of nonces used to reject replayed requests. Defaults to a MemoryNonceCache.
+func WithNonceCache(nonces NonceCache) Option {
+	return func(o *options) {
+		o.nonces = nonces
+	}
+}
+
+// WithRequiredHeaders rejects requests that do not sign the metadata headers.
+func WithRequiredHeaders(names ...string) Option {
+	return func(o *options) {
+		for _, name := range names {
+			o.requiredHeaders = append(o.requiredHeaders, strings.ToLower(name))
+		}
+	}
+}
+
+// WithTimeFunc sets the clock requests' timestamps are checked against. Defaults to time.Now.
+func WithTimeFunc(timeFunc func() time.Time) Option {
+	return func(o *options) {
+		o.timeFunc = timeFunc
+	}
+}
+
+type clientOptions struct {
+	signedHeaders []string
+}
+
+func evaluateClientOptions(opts []ClientOption) *clientOptions {
+	o := &clientOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// ClientOption configures how the client interceptors sign requests.
+type ClientOption func(*clientOptions)
+
+// WithSignedHeaders signs the outgoing metadata headers, e.g. `x-request-id`, in addition to the method, timestamp,
+// nonce and request. The `x-hmac-*` headers of the signature itself are ignored.
+func WithSignedHeaders(names ...string) ClientOption {
+	return func(o *clientOptions) {
+		for _, name := range names {
+			name = strings.ToLower(name)
+			if !strings.HasPrefix(name, headerPrefix) {
+				o.signedHeaders = append(o.signedHeaders, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_hmac
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rkollar/go-grpc-middleware"
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"github.com/rkollar/go-grpc-middleware/tags"
+	"github.com/rkollar/go-grpc-middleware/util/metautils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type capturedCallKey struct{}
+
+var errInvalidSignature = status.Errorf(codes.Unauthenticated, "Invalid request signature")
+
+// capturedCall is the part of a call that is signed, but not available to a grpc_auth.AuthFunc.
+type capturedCall struct {
+	fullMethod string
+	body       []byte
+	err        error
+	// deferred is set for server streaming calls, whose request is only received after the call is
+	// authenticated. AuthFunc then sets verifyBody, which the stream calls with the received request.
+	deferred   bool
+	verifyBody func(body []byte) error
+	// verified is set atomically once verifyBody succeeds.
+	verified int32
+}
+
+// verifiedContext is the context AuthFunc returns for server streaming calls. It only exposes the values of the
+// authenticated context, such as the Principal, once the request of the call is verified.
+type verifiedContext struct {
+	context.Context
+	authenticated context.Context
+	call          *capturedCall
+}
+
+func (c *verifiedContext) Value(key interface{}) interface{} {
+	if atomic.LoadInt32(&c.call.verified) == 1 {
+		return c.authenticated.Value(key)
+	}
+	return c.Context.Value(key)
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor that makes the method and request of calls
+// available to AuthFunc. It must run before the grpc_auth interceptor.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		call := &capturedCall{fullMethod: info.FullMethod}
+		call.body, call.err = marshalMessage(req)
+		return handler(context.WithValue(ctx, capturedCallKey{}, call), req)
+	}
+}
+
+// StreamServerInterceptor returns a new streaming server interceptor that makes the method of calls available to
+// AuthFunc. It must run before the grpc_auth interceptor.
+//
+// The request of server streaming (1:n) calls is signed too, but only received once the call is authenticated.
+// Its signature is verified when the handler receives it, which fails with `Unauthenticated` if it is invalid.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		call := &capturedCall{fullMethod: info.FullMethod, deferred: !info.IsClientStream}
+		ctx := context.WithValue(stream.Context(), capturedCallKey{}, call)
+		if !call.deferred {
+			wrapped := grpc_middleware.WrapServerStream(stream)
+			wrapped.WrappedContext = ctx
+			return handler(srv, wrapped)
+		}
+		return handler(srv, &verifyingServerStream{ServerStream: stream, ctx: ctx, call: call})
+	}
+}
+
+// verifyingServerStream is a grpc.ServerStream verifying the signature of the request of a server streaming call.
+type verifyingServerStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	call     *capturedCall
+	received bool
+}
+
+func (s *verifyingServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *verifyingServerStream) RecvMsg(m interface{}) error {
+	if s.received {
+		return s.ServerStream.RecvMsg(m)
+	}
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	s.received = true
+	if s.call.verifyBody == nil {
+		return nil
+	}
+	body, err := marshalMessage(m)
+	if err != nil {
+		return status.Errorf(codes.Internal, "grpc_hmac: failed to verify request: %v", err)
+	}
+	return s.call.verifyBody(body)
+}
+
+// Middleware returns a new grpc_middleware.Middleware that makes calls verifiable by AuthFunc on the server side.
+func Middleware() grpc_middleware.Middleware {
+	return grpc_middleware.Middleware{
+		UnaryServer:  UnaryServerInterceptor(),
+		StreamServer: StreamServerInterceptor(),
+	}
+}
+
+// AuthFunc returns a grpc_auth.AuthFunc that authenticates calls by their HMAC signature.
+//
+// Calls that are not signed, are signed with an unknown key, have an invalid signature, a timestamp outside of
+// the allowed clock skew or a replayed nonce fail with `Unauthenticated`. The key ID is stored in the context as
+// the name of the grpc_auth.Principal, and set as the `auth.hmac_key_id` grpc_ctxtags.
+//
+// The signature of server streaming calls is only verified once their request is received, see
+// StreamServerInterceptor. Until then their nonce is not recorded, and neither the Principal nor the tag is
+// available, so interceptors that run before the handler receives the request, e.g. grpc_authz, see the call
+// as unauthenticated.
+//
+// The Middleware of this package must run before grpc_auth, otherwise all calls fail with `Internal`.
+func AuthFunc(keys KeyStore, opts ...Option) grpc_auth.AuthFunc {
+	o := evaluateOptions(opts)
+	return func(ctx context.Context) (context.Context, error) {
+		captured, ok := ctx.Value(capturedCallKey{}).(*capturedCall)
+		if !ok {
+			return nil, status.Errorf(codes.Internal, "grpc_hmac: call not captured, grpc_hmac.Middleware must run before grpc_auth")
+		}
+		if captured.err != nil {
+			return nil, status.Errorf(codes.Internal, "grpc_hmac: failed to verify request: %v", captured.err)
+		}
+		md := metautils.ExtractIncoming(ctx)
+		keyID, timestamp, nonce := md.Get(headerKeyID), md.Get(headerTimestamp), md.Get(headerNonce)
+		signature, err := base64.StdEncoding.DecodeString(md.Get(headerSignature))
+		if keyID == "" || timestamp == "" || nonce == "" || len(signature) == 0 || err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "Request unauthenticated with HMAC signature")
+		}
+		unix, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "Bad request timestamp")
+		}
+		signedAt := time.Unix(unix, 0)
+		now := o.timeFunc()
+		if skew := now.Sub(signedAt); skew > o.maxClockSkew || skew < -o.maxClockSkew {
+			return nil, status.Errorf(codes.Unauthenticated, "Request timestamp outside of allowed clock skew")
+		}
+		headerNames, err := o.signedHeaders(md.Get(headerSignedHeaders))
+		if err != nil {
+			return nil, err
+		}
+		key, err := keys.Key(ctx, keyID)
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "grpc_hmac: failed to look up key: %v", err)
+		}
+		call := &signedCall{
+			fullMethod:  captured.fullMethod,
+			timestamp:   timestamp,
+			nonce:       nonce,
+			headerNames: headerNames,
+			md:          metadata.MD(md),
+			body:        captured.body,
+		}
+		if key == nil {
+			return nil, errInvalidSignature
+		}
+		// accept records the nonce of a call with a valid signature, and tags the call with its key ID.
+		accept := func() error {
+			added, err := o.nonces.Add(ctx, keyID, nonce, now, signedAt.Add(o.maxClockSkew))
+			if err != nil {
+				return status.Errorf(codes.Unavailable, "grpc_hmac: failed to check nonce: %v", err)
+			}
+			if !added {
+				return status.Errorf(codes.Unauthenticated, "Replayed request")
+			}
+			grpc_ctxtags.Extract(ctx).Set("auth.hmac_key_id", keyID)
+			return nil
+		}
+		authenticated := grpc_auth.ContextWithPrincipal(ctx, &grpc_auth.Principal{Name: keyID})
+		if captured.deferred {
+			captured.verifyBody = func(body []byte) error {
+				call.body = body
+				if !hmac.Equal(call.sign(key), signature) {
+					return errInvalidSignature
+				}
+				if err := accept(); err != nil {
+					return err
+				}
+				atomic.StoreInt32(&captured.verified, 1)
+				return nil
+			}
+			return &verifiedContext{Context: ctx, authenticated: authenticated, call: captured}, nil
+		}
+		if !hmac.Equal(call.sign(key), signature) {
+			return nil, errInvalidSignature
+		}
+		if err := accept(); err != nil {
+			return nil, err
+		}
+		return authenticated, nil
+	}
+}
+
+// signedHeaders parses the names of the signed headers, checking that all required headers are signed.
+func (o *options) signedHeaders(list string) ([]string, error) {
+	var names []string
+	if list != "" {
+		names = strings.Split(list, ",")
+	}
+	for _, name := range names {
+		if name == "" || strings.HasPrefix(name, headerPrefix) {
+			return nil, status.Errorf(codes.Unauthenticated, "Bad signed headers")
+		}
+	}
+	for _, required := range o.requiredHeaders {
+		signed := false
+		for _, name := range names {
+			signed = signed || name == required
+		}
+		if !signed {
+			return nil, status.Errorf(codes.Unauthenticated, "Header %q must be signed", required)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_hmac
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"strconv"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	headerPrefix        = "x-hmac-"
+	headerKeyID         = "x-hmac-key-id"
+	headerTimestamp     = "x-hmac-timestamp"
+	headerNonce         = "x-hmac-nonce"
+	headerSignedHeaders = "x-hmac-signed-headers"
+	headerSignature     = "x-hmac-signature"
+
+	signatureVersion = "grpc-hmac-v1"
+)
+
+// signedCall is the content of a call that is signed.
+type signedCall struct {
+	fullMethod  string
+	timestamp   string
+	nonce       string
+	headerNames []string
+	md          metadata.MD
+	body        []byte
+}
+
+// sign computes the HMAC-SHA256 signature of the call with the key.
+//
+// All fields are written with their length in front, so that no two different calls have the same input.
+func (c *signedCall) sign(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	writeField(mac, signatureVersion)
+	writeField(mac, c.fullMethod)
+	writeField(mac, c.timestamp)
+	writeField(mac, c.nonce)
+	writeField(mac, strconv.Itoa(len(c.headerNames)))
+	for _, name := range c.headerNames {
+		values := c.md.Get(name)
+		writeField(mac, name)
+		writeField(mac, strconv.Itoa(len(values)))
+		for _, v := range values {
+			writeField(mac, v)
+		}
+	}
+	bodySum := sha256.Sum256(c.body)
+	mac.Write(bodySum[:])
+	return mac.Sum(nil)
+}
+
+func writeField(h hash.Hash, field string) {
+	fmt.Fprintf(h, "%d:%s", len(field), field)
+}
+
+// marshalMessage serializes the message deterministically, so that the client and the server serialize it
+// the same way.
+func marshalMessage(m interface{}) ([]byte, error) {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("message of type %T is not a proto message", m)
+	}
+	buf := proto.NewBuffer(nil)
+	buf.SetDeterministic(true)
+	if err := buf.Marshal(msg); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
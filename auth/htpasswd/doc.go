@@ -0,0 +1,15 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`grpc_htpasswd` provides a `grpc_auth.AuthFunc` that verifies Basic auth credentials against an htpasswd file.
+
+Basic Auth Middleware
+
+`AuthFunc` decodes the credentials with `grpc_auth.BasicAuthFromMD` and verifies them against the bcrypt
+hashes of a `File`, as created with `htpasswd -B`. The file is reloaded when it changes, so users can be
+managed without restarting the server.
+
+Please see examples for simple examples of use.
+*/
+package grpc_htpasswd
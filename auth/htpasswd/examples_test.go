@@ -0,0 +1,25 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_htpasswd_test
+
+import (
+	"time"
+
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"github.com/rkollar/go-grpc-middleware/auth/htpasswd"
+	"google.golang.org/grpc"
+)
+
+// Simple example of server initialization code, verifying Basic auth credentials of internal tooling.
+func Example_serverConfig() {
+	file, err := grpc_htpasswd.NewFile("/etc/myservice/htpasswd", 10*time.Second)
+	if err != nil {
+		panic(err)
+	}
+	authFunc := grpc_htpasswd.AuthFunc(file)
+	_ = grpc.NewServer(
+		grpc.StreamInterceptor(grpc_auth.StreamServerInterceptor(authFunc)),
+		grpc.UnaryInterceptor(grpc_auth.UnaryServerInterceptor(authFunc)),
+	)
+}
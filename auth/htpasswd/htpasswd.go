@@ -0,0 +1,140 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_htpasswd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"github.com/rkollar/go-grpc-middleware/tags"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// dummyHash is verified against for unknown users, so that they take about as long as known ones.
+	dummyHash     []byte
+	dummyHashOnce sync.Once
+)
+
+// File is a set of users and their bcrypt password hashes, read from an htpasswd file.
+//
+// Each line of the file has the form `user:hash`, as created by `htpasswd -B`. Blank lines and lines starting
+// with `#` are ignored. Only bcrypt hashes are supported, as the other htpasswd formats are insecure.
+//
+// The file is reloaded when its modification time changes, checked at most once per reload interval, so that
+// users can be added and removed without restarting the server. If reloading fails, the previously loaded
+// users stay in use.
+type File struct {
+	path           string
+	reloadInterval time.Duration
+
+	mu        sync.Mutex
+	users     map[string][]byte
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// NewFile creates a File, loading the htpasswd file at the path.
+func NewFile(path string, reloadInterval time.Duration) (*File, error) {
+	f := &File{path: path, reloadInterval: reloadInterval}
+	if err := f.reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Parse parses the contents of an htpasswd file into users and their password hashes.
+func Parse(data []byte) (map[string][]byte, error) {
+	users := map[string][]byte{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		splits := strings.SplitN(line, ":", 2)
+		if len(splits) < 2 || splits[0] == "" {
+			return nil, fmt.Errorf("grpc_htpasswd: malformed line %d", lineNo)
+		}
+		if _, err := bcrypt.Cost([]byte(splits[1])); err != nil {
+			return nil, fmt.Errorf("grpc_htpasswd: unsupported hash of user %q on line %d, only bcrypt is supported", splits[0], lineNo)
+		}
+		users[splits[0]] = []byte(splits[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("grpc_htpasswd: failed to read file: %v", err)
+	}
+	return users, nil
+}
+
+// Verify returns whether the password of the user matches its hash.
+func (f *File) Verify(user string, password string) bool {
+	f.mu.Lock()
+	if time.Since(f.checkedAt) >= f.reloadInterval {
+		// Errors keep the users loaded last, and are retried after the reload interval.
+		_ = f.reload()
+	}
+	hash, ok := f.users[user]
+	f.mu.Unlock()
+	if !ok {
+		dummyHashOnce.Do(func() {
+			dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy"), bcrypt.DefaultCost)
+		})
+		hash = dummyHash
+	}
+	return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil && ok
+}
+
+// reload reads the file if it changed since it was last read.
+func (f *File) reload() error {
+	f.checkedAt = time.Now()
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return fmt.Errorf("grpc_htpasswd: failed to read file: %v", err)
+	}
+	if f.users != nil && info.ModTime().Equal(f.modTime) {
+		return nil
+	}
+	data, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("grpc_htpasswd: failed to read file: %v", err)
+	}
+	users, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	f.users = users
+	f.modTime = info.ModTime()
+	return nil
+}
+
+// AuthFunc returns a grpc_auth.AuthFunc that verifies the Basic auth credentials of calls against the file.
+//
+// Calls with missing or wrong credentials fail with `Unauthenticated`. The user is stored in the context as
+// the name of a grpc_auth.Principal, and set as the `auth.user` grpc_ctxtags.
+//
+// Verifying bcrypt hashes is deliberately slow, so consider caching the results for busy services.
+func AuthFunc(file *File) grpc_auth.AuthFunc {
+	return func(ctx context.Context) (context.Context, error) {
+		user, password, err := grpc_auth.BasicAuthFromMD(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !file.Verify(user, password) {
+			return nil, status.Errorf(codes.Unauthenticated, "invalid user or password")
+		}
+		grpc_ctxtags.Extract(ctx).Set("auth.user", user)
+		return grpc_auth.ContextWithPrincipal(ctx, &grpc_auth.Principal{Name: user}), nil
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_htpasswd
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func htpasswdLine(t *testing.T, user, password string) string {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+	return user + ":" + string(hash) + "\n"
+}
+
+func writeFile(t *testing.T, path string, modTime time.Time, content string) {
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func basicAuthContext(user, password string) context.Context {
+	credentials := base64.StdEncoding.EncodeToString([]byte(user + ":" + password))
+	return metadata.NewIncomingContext(context.TODO(), metadata.Pairs("authorization", "Basic "+credentials))
+}
+
+func TestParse(t *testing.T) {
+	users, err := Parse([]byte("# tooling users\n\n" + htpasswdLine(t, "alice", "secret") + htpasswdLine(t, "bob", "other")))
+	require.NoError(t, err)
+	assert.Len(t, users, 2, "comments and blank lines must be skipped")
+
+	for _, content := range []string{
+		"alice\n",
+		":" + string(users["alice"]) + "\n",
+		"alice:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=\n",
+		"alice:$apr1$salt$hash\n",
+		"alice:plaintext\n",
+	} {
+		_, err := Parse([]byte(content))
+		assert.Error(t, err, "%q must be rejected", content)
+	}
+}
+
+func TestAuthFunc(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grpc_htpasswd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "htpasswd")
+	now := time.Now()
+	writeFile(t, path, now, htpasswdLine(t, "alice", "secret"))
+
+	file, err := NewFile(path, 0)
+	require.NoError(t, err)
+	authFunc := AuthFunc(file)
+
+	ctx, err := authFunc(basicAuthContext("alice", "secret"))
+	require.NoError(t, err, "valid credentials must be accepted")
+	principal, ok := grpc_auth.PrincipalFromContext(ctx)
+	require.True(t, ok, "principal must be stored in the context")
+	assert.Equal(t, "alice", principal.Name)
+
+	for _, ctx := range []context.Context{
+		basicAuthContext("alice", "wrong"),
+		basicAuthContext("mallory", "secret"),
+		context.TODO(),
+	} {
+		_, err := authFunc(ctx)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), "invalid credentials must be rejected")
+	}
+
+	writeFile(t, path, now.Add(time.Second), htpasswdLine(t, "bob", "other"))
+	_, err = authFunc(basicAuthContext("bob", "other"))
+	assert.NoError(t, err, "added users must be picked up")
+	_, err = authFunc(basicAuthContext("alice", "secret"))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "removed users must be picked up")
+
+	writeFile(t, path, now.Add(2*time.Second), "broken\n")
+	_, err = authFunc(basicAuthContext("bob", "other"))
+	assert.NoError(t, err, "users loaded last must stay in use if the file is broken")
+
+	_, err = NewFile(filepath.Join(dir, "missing"), time.Minute)
+	assert.Error(t, err)
+}
@@ -0,0 +1,143 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_jwt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/rkollar/go-grpc-middleware/auth"
+)
+
+// Claims are the registered claims of a verified token (RFC 7519, section 4.1).
+//
+// Times are zero if the token does not carry the claim. Use Decode to access other claims.
+type Claims struct {
+	Issuer    string
+	Subject   string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	ID        string
+
+	raw json.RawMessage
+}
+
+// Decode unmarshals the full claims set of the token into v, e.g. a struct with custom claims.
+func (c *Claims) Decode(v interface{}) error {
+	return json.Unmarshal(c.raw, v)
+}
+
+type ctxMarker struct{}
+
+var ctxMarkerKey = &ctxMarker{}
+
+// ClaimsFromContext returns the claims of the token verified by AuthFunc for the call.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(ctxMarkerKey).(*Claims)
+	return claims, ok
+}
+
+// ContextWithClaims returns a copy of the context carrying the claims, e.g. for tests of handlers.
+func ContextWithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, ctxMarkerKey, claims)
+}
+
+// principal maps the claims to the caller: the subject names it, the roles claim holds its roles, and the
+// `scope` (RFC 8693) or `scp` claim holds its scopes.
+func (c *Claims) principal(rolesClaim string) (*grpc_auth.Principal, error) {
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(c.raw, &all); err != nil {
+		return nil, err
+	}
+	principal := &grpc_auth.Principal{Name: c.Subject}
+	var err error
+	if principal.Roles, err = parseStringList(all[rolesClaim]); err != nil {
+		return nil, fmt.Errorf("%s: %v", rolesClaim, err)
+	}
+	scopes := all["scope"]
+	if scopes == nil {
+		scopes = all["scp"]
+	}
+	if principal.Scopes, err = parseStringList(scopes); err != nil {
+		return nil, fmt.Errorf("scopes: %v", err)
+	}
+	return principal, nil
+}
+
+// parseStringList parses a claim that is either an array of strings or a space-delimited string.
+func parseStringList(data json.RawMessage) ([]string, error) {
+	if data == nil {
+		return nil, nil
+	}
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		return strings.Fields(single), nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return nil, fmt.Errorf("must be a string or an array of strings")
+	}
+	return multiple, nil
+}
+
+type registeredClaims struct {
+	Issuer    string       `json:"iss"`
+	Subject   string       `json:"sub"`
+	Audience  audience     `json:"aud"`
+	ExpiresAt *numericDate `json:"exp"`
+	NotBefore *numericDate `json:"nbf"`
+	IssuedAt  *numericDate `json:"iat"`
+	ID        string       `json:"jti"`
+}
+
+func parseClaims(payload []byte) (*Claims, error) {
+	var reg registeredClaims
+	if err := json.Unmarshal(payload, &reg); err != nil {
+		return nil, err
+	}
+	return &Claims{
+		Issuer:    reg.Issuer,
+		Subject:   reg.Subject,
+		Audience:  reg.Audience,
+		ExpiresAt: reg.ExpiresAt.time(),
+		NotBefore: reg.NotBefore.time(),
+		IssuedAt:  reg.IssuedAt.time(),
+		ID:        reg.ID,
+		raw:       payload,
+	}, nil
+}
+
+// audience is the `aud` claim, which is either a single string or an array of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return fmt.Errorf("aud must be a string or an array of strings")
+	}
+	*a = multiple
+	return nil
+}
+
+// numericDate is a JSON number of seconds since the epoch, possibly fractional.
+type numericDate float64
+
+func (d *numericDate) time() time.Time {
+	if d == nil {
+		return time.Time{}
+	}
+	sec, frac := math.Modf(float64(*d))
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
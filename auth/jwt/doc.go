@@ -0,0 +1,20 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`grpc_jwt` provides a `grpc_auth.AuthFunc` that verifies JSON Web Tokens.
+
+JWT Authentication
+
+`AuthFunc` extracts a bearer token from the `:authorization` header, verifies its signature and
+validates its `exp`, `nbf`, `iss` and `aud` claims, with a configurable leeway for clock skew. The
+claims of verified tokens are stored in the context of handlers, see `ClaimsFromContext`, along with a
+`grpc_auth.Principal` named by the `sub` claim, carrying the roles and scopes of the token.
+
+Tokens signed with HS256, RS256 and ES256 are supported. The keys are provided by a `KeySet`: either
+`StaticKeys` configured in code or loaded from a JSON Web Key Set file with `LoadJWKSFile`, or a
+`RemoteJWKS` fetched (and periodically refreshed) from the URL of an identity provider.
+
+Please see examples for simple examples of use.
+*/
+package grpc_jwt
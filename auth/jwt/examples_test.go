@@ -0,0 +1,46 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_jwt_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"github.com/rkollar/go-grpc-middleware/auth/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Simple example of server initialization code verifying tokens of an identity provider.
+func Example_serverConfig() {
+	keys := grpc_jwt.NewRemoteJWKS("https://issuer.example.com/.well-known/jwks.json", nil, 1*time.Hour)
+	authFunc := grpc_jwt.AuthFunc(keys,
+		grpc_jwt.WithIssuer("https://issuer.example.com"),
+		grpc_jwt.WithAudience("my-api"),
+		grpc_jwt.WithLeeway(30*time.Second),
+	)
+	_ = grpc.NewServer(
+		grpc.StreamInterceptor(grpc_auth.StreamServerInterceptor(authFunc)),
+		grpc.UnaryInterceptor(grpc_auth.UnaryServerInterceptor(authFunc)),
+	)
+}
+
+// Simple example of a handler reading the claims of the verified token.
+func ExampleClaimsFromContext() {
+	_ = func(ctx context.Context) error {
+		claims, ok := grpc_jwt.ClaimsFromContext(ctx)
+		if !ok {
+			return status.Errorf(codes.Unauthenticated, "no token")
+		}
+		var custom struct {
+			Roles []string `json:"roles"`
+		}
+		if err := claims.Decode(&custom); err != nil {
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Supported signature algorithms.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+// Verifier verifies signed JSON Web Tokens (RFC 7519) in compact serialization.
+type Verifier struct {
+	keys KeySet
+	opts *options
+}
+
+// NewVerifier creates a Verifier of tokens signed with keys from the KeySet.
+func NewVerifier(keys KeySet, opts ...Option) *Verifier {
+	return &Verifier{keys: keys, opts: evaluateOptions(opts)}
+}
+
+// Verify checks the signature of the token and validates its `exp`, `nbf`, `iss` and `aud` claims.
+//
+// Errors have the gRPC status `Unauthenticated`, apart from errors fetching the keys, which are `Unavailable`.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, invalidToken("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, invalidToken("malformed header")
+	}
+	if !contains(v.opts.algorithms, header.Alg) {
+		return nil, invalidToken("unsupported algorithm %q", header.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, invalidToken("malformed signature")
+	}
+	keys, err := v.keys.Keys(ctx, header.Kid)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "grpc_jwt: failed to obtain keys: %v", err)
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, key := range keys {
+		if verifySignature(header.Alg, key, signed, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, invalidToken("signature verification failed")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, invalidToken("malformed payload")
+	}
+	claims, err := parseClaims(payload)
+	if err != nil {
+		return nil, invalidToken("malformed claims")
+	}
+	if err := v.validate(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (v *Verifier) validate(claims *Claims) error {
+	now := v.opts.timeFunc()
+	if !claims.ExpiresAt.IsZero() && now.After(claims.ExpiresAt.Add(v.opts.leeway)) {
+		return invalidToken("token expired")
+	}
+	if !claims.NotBefore.IsZero() && now.Before(claims.NotBefore.Add(-v.opts.leeway)) {
+		return invalidToken("token not valid yet")
+	}
+	if len(v.opts.issuers) > 0 && !contains(v.opts.issuers, claims.Issuer) {
+		return invalidToken("unexpected issuer %q", claims.Issuer)
+	}
+	if len(v.opts.audiences) > 0 {
+		for _, aud := range claims.Audience {
+			if contains(v.opts.audiences, aud) {
+				return nil
+			}
+		}
+		return invalidToken("unexpected audience")
+	}
+	return nil
+}
+
+// AuthFunc returns a grpc_auth.AuthFunc that verifies the token of the `authorization` header and stores its
+// claims in the context, see ClaimsFromContext. The expiry of the token is stored too, see
+// grpc_auth.ExpiryFromContext.
+//
+// The caller is stored as a grpc_auth.Principal named by the `sub` claim, with the roles of the roles claim
+// (see WithRolesClaim) and the scopes of the `scope` or `scp` claim.
+func AuthFunc(keys KeySet, opts ...Option) grpc_auth.AuthFunc {
+	v := NewVerifier(keys, opts...)
+	return func(ctx context.Context) (context.Context, error) {
+		token, err := grpc_auth.AuthFromMD(ctx, v.opts.scheme)
+		if err != nil {
+			return nil, err
+		}
+		claims, err := v.Verify(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		principal, err := claims.principal(v.opts.rolesClaim)
+		if err != nil {
+			return nil, invalidToken("malformed claims")
+		}
+		if !claims.ExpiresAt.IsZero() {
+			ctx = grpc_auth.ContextWithExpiry(ctx, claims.ExpiresAt)
+		}
+		ctx = grpc_auth.ContextWithPrincipal(ctx, principal)
+		return ContextWithClaims(ctx, claims), nil
+	}
+}
+
+func verifySignature(alg string, key interface{}, signed, signature []byte) bool {
+	digest := sha256.Sum256(signed)
+	switch alg {
+	case HS256:
+		secret, ok := key.([]byte)
+		if !ok {
+			return false
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signed)
+		return hmac.Equal(mac.Sum(nil), signature)
+	case RS256:
+		pub, ok := key.(*rsa.PublicKey)
+		return ok && rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) == nil
+	case ES256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve != elliptic.P256() || len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(pub, digest[:], r, s)
+	}
+	return false
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func invalidToken(format string, a ...interface{}) error {
+	return status.Errorf(codes.Unauthenticated, "invalid token: %s", fmt.Sprintf(format, a...))
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,253 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	hmacSecret = []byte("some_secret")
+	rsaKey     *rsa.PrivateKey
+	ecKey      *ecdsa.PrivateKey
+	testNow    = time.Unix(1600000000, 0)
+)
+
+func init() {
+	var err error
+	if rsaKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		panic(err)
+	}
+	if ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		panic(err)
+	}
+}
+
+func sign(t *testing.T, alg string, kid string, claims map[string]interface{}) string {
+	header := map[string]interface{}{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := encode(header) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signed))
+	var signature []byte
+	switch alg {
+	case HS256:
+		mac := hmac.New(sha256.New, hmacSecret)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case RS256:
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	case ES256:
+		r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+		require.NoError(t, err)
+		signature = make([]byte, 64)
+		rb, sb := r.Bytes(), s.Bytes()
+		copy(signature[32-len(rb):32], rb)
+		copy(signature[64-len(sb):], sb)
+	default:
+		signature = []byte("unsigned")
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":  "https://issuer.example.com",
+		"sub":  "user-1",
+		"aud":  "api",
+		"exp":  testNow.Add(time.Hour).Unix(),
+		"nbf":  testNow.Add(-time.Hour).Unix(),
+		"iat":  testNow.Add(-time.Hour).Unix(),
+		"role": "admin",
+	}
+}
+
+func testKeys() StaticKeys {
+	return StaticKeys{
+		"hmac": hmacSecret,
+		"rsa":  &rsaKey.PublicKey,
+		"ec":   &ecKey.PublicKey,
+	}
+}
+
+func TestVerifier_Algorithms(t *testing.T) {
+	v := NewVerifier(testKeys(), WithTimeFunc(func() time.Time { return testNow }))
+	for _, alg := range []string{HS256, RS256, ES256} {
+		t.Run(alg, func(t *testing.T) {
+			claims, err := v.Verify(context.TODO(), sign(t, alg, "", validClaims()))
+			require.NoError(t, err, "token without key ID must be verified against all keys")
+			assert.Equal(t, "user-1", claims.Subject)
+			assert.Equal(t, []string{"api"}, claims.Audience)
+			assert.Equal(t, testNow.Add(time.Hour), claims.ExpiresAt)
+		})
+	}
+}
+
+func TestVerifier_RejectsInvalidTokens(t *testing.T) {
+	v := NewVerifier(testKeys(), WithTimeFunc(func() time.Time { return testNow }))
+	tampered := sign(t, HS256, "hmac", validClaims())
+	tampered = tampered[:len(tampered)-4] + "AAAA"
+	for _, tcase := range []struct {
+		name  string
+		token string
+	}{
+		{"malformed", "not.a-token"},
+		{"tampered signature", tampered},
+		{"unsupported algorithm", sign(t, "none", "", validClaims())},
+		{"wrong key ID", sign(t, RS256, "ec", validClaims())},
+		{"unknown key ID", sign(t, HS256, "other", validClaims())},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			_, err := v.Verify(context.TODO(), tcase.token)
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		})
+	}
+}
+
+func TestVerifier_RejectsAlgorithmConfusion(t *testing.T) {
+	// An HS256 token signed with the public key bytes must not verify against the RSA key.
+	keys := StaticKeys{"rsa": &rsaKey.PublicKey}
+	v := NewVerifier(keys, WithTimeFunc(func() time.Time { return testNow }))
+	_, err := v.Verify(context.TODO(), sign(t, HS256, "rsa", validClaims()))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "keys must only be used for their algorithm")
+
+	v = NewVerifier(testKeys(), WithAlgorithms(RS256), WithTimeFunc(func() time.Time { return testNow }))
+	_, err = v.Verify(context.TODO(), sign(t, HS256, "hmac", validClaims()))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "algorithms not allowed must be rejected")
+}
+
+func TestVerifier_ValidatesClaims(t *testing.T) {
+	for _, tcase := range []struct {
+		name    string
+		now     time.Time
+		opts    []Option
+		mutate  func(map[string]interface{})
+		message string
+	}{
+		{"expired", testNow.Add(2 * time.Hour), nil, nil, "token expired"},
+		{"expired within leeway", testNow.Add(time.Hour + time.Minute), []Option{WithLeeway(2 * time.Minute)}, nil, ""},
+		{"not valid yet", testNow.Add(-2 * time.Hour), nil, nil, "token not valid yet"},
+		{"not valid yet within leeway", testNow.Add(-time.Hour - time.Minute), []Option{WithLeeway(2 * time.Minute)}, nil, ""},
+		{"issuer", testNow, []Option{WithIssuer("https://other.example.com")}, nil, "unexpected issuer"},
+		{"matching issuer", testNow, []Option{WithIssuer("https://other.example.com", "https://issuer.example.com")}, nil, ""},
+		{"audience", testNow, []Option{WithAudience("other")}, nil, "unexpected audience"},
+		{"audience array", testNow, []Option{WithAudience("api")}, func(c map[string]interface{}) { c["aud"] = []string{"other", "api"} }, ""},
+		{"missing audience", testNow, []Option{WithAudience("api")}, func(c map[string]interface{}) { delete(c, "aud") }, "unexpected audience"},
+		{"no expiry", testNow.Add(100 * time.Hour), nil, func(c map[string]interface{}) { delete(c, "exp") }, ""},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			now := tcase.now
+			v := NewVerifier(testKeys(), append(tcase.opts, WithTimeFunc(func() time.Time { return now }))...)
+			claims := validClaims()
+			if tcase.mutate != nil {
+				tcase.mutate(claims)
+			}
+			_, err := v.Verify(context.TODO(), sign(t, ES256, "ec", claims))
+			if tcase.message == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Equal(t, codes.Unauthenticated, status.Code(err))
+			assert.Contains(t, status.Convert(err).Message(), tcase.message)
+		})
+	}
+}
+
+func TestAuthFunc(t *testing.T) {
+	authFunc := AuthFunc(testKeys(), WithAudience("api"), WithTimeFunc(func() time.Time { return testNow }))
+	md := metadata.Pairs("authorization", "Bearer "+sign(t, RS256, "rsa", validClaims()))
+	ctx, err := authFunc(metadata.NewIncomingContext(context.TODO(), md))
+	require.NoError(t, err)
+
+	claims, ok := ClaimsFromContext(ctx)
+	require.True(t, ok, "claims must be stored in the context")
+	assert.Equal(t, "https://issuer.example.com", claims.Issuer)
+	var custom struct {
+		Role string `json:"role"`
+	}
+	require.NoError(t, claims.Decode(&custom))
+	assert.Equal(t, "admin", custom.Role, "custom claims must be decodable")
+	expiresAt, ok := grpc_auth.ExpiryFromContext(ctx)
+	require.True(t, ok, "expiry of the token must be stored in the context")
+	assert.Equal(t, claims.ExpiresAt, expiresAt)
+	principal, ok := grpc_auth.PrincipalFromContext(ctx)
+	require.True(t, ok, "principal must be stored in the context")
+	assert.Equal(t, &grpc_auth.Principal{Name: "user-1"}, principal, "principal must be named by the subject")
+
+	_, err = authFunc(context.TODO())
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "calls without a token must be rejected")
+	_, ok = ClaimsFromContext(context.TODO())
+	assert.False(t, ok)
+}
+
+func TestAuthFunc_Principal(t *testing.T) {
+	for _, tcase := range []struct {
+		name      string
+		opts      []Option
+		claims    map[string]interface{}
+		principal *grpc_auth.Principal
+	}{
+		{
+			name:      "roles array and scope string",
+			claims:    map[string]interface{}{"roles": []string{"admin", "user"}, "scope": "read write"},
+			principal: &grpc_auth.Principal{Name: "user-1", Roles: []string{"admin", "user"}, Scopes: []string{"read", "write"}},
+		},
+		{
+			name:      "scp array",
+			claims:    map[string]interface{}{"scp": []string{"read"}},
+			principal: &grpc_auth.Principal{Name: "user-1", Scopes: []string{"read"}},
+		},
+		{
+			name:      "custom roles claim",
+			opts:      []Option{WithRolesClaim("role")},
+			principal: &grpc_auth.Principal{Name: "user-1", Roles: []string{"admin"}},
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			claims := validClaims()
+			for k, v := range tcase.claims {
+				claims[k] = v
+			}
+			authFunc := AuthFunc(testKeys(), append(tcase.opts, WithTimeFunc(func() time.Time { return testNow }))...)
+			md := metadata.Pairs("authorization", "Bearer "+sign(t, HS256, "hmac", claims))
+			ctx, err := authFunc(metadata.NewIncomingContext(context.TODO(), md))
+			require.NoError(t, err)
+			principal, ok := grpc_auth.PrincipalFromContext(ctx)
+			require.True(t, ok, "principal must be stored in the context")
+			assert.Equal(t, tcase.principal, principal)
+		})
+	}
+
+	claims := validClaims()
+	claims["roles"] = map[string]interface{}{"admin": true}
+	md := metadata.Pairs("authorization", "Bearer "+sign(t, HS256, "hmac", claims))
+	_, err := AuthFunc(testKeys(), WithTimeFunc(func() time.Time { return testNow }))(metadata.NewIncomingContext(context.TODO(), md))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "tokens with malformed roles must be rejected")
+}
@@ -0,0 +1,287 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// KeySet provides the keys that token signatures are verified against.
+//
+// Keys are []byte secrets for HS256, *rsa.PublicKey for RS256 and *ecdsa.PublicKey for ES256.
+type KeySet interface {
+	// Keys returns the candidate keys for a token with the given key ID, which is empty if the token has none.
+	Keys(ctx context.Context, kid string) ([]interface{}, error)
+}
+
+// StaticKeys is a KeySet of keys indexed by key ID.
+//
+// Tokens with a key ID are verified against the key with the same ID only, tokens without one against all keys.
+type StaticKeys map[string]interface{}
+
+// Keys implements KeySet.
+func (s StaticKeys) Keys(_ context.Context, kid string) ([]interface{}, error) {
+	if kid != "" {
+		if key, ok := s[kid]; ok {
+			return []interface{}{key}, nil
+		}
+		return nil, nil
+	}
+	keys := make([]interface{}, 0, len(s))
+	for _, key := range s {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// ParseJWKS parses a JSON Web Key Set document (RFC 7517).
+//
+// RSA, P-256 EC and symmetric (oct) keys are supported. Keys of other types, and keys meant for encryption,
+// are skipped. Keys without a key ID are indexed by their position in the set, prefixed with a NUL byte, so
+// that tokens without a key ID are verified against each of them.
+func ParseJWKS(data []byte) (StaticKeys, error) {
+	var doc struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("grpc_jwt: malformed JWKS: %v", err)
+	}
+	keys := StaticKeys{}
+	for i, jwk := range doc.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.key()
+		if err != nil {
+			return nil, fmt.Errorf("grpc_jwt: malformed JWKS key %d: %v", i, err)
+		}
+		if key == nil {
+			continue
+		}
+		kid := jwk.Kid
+		if kid == "" {
+			kid = fmt.Sprintf("\x00%d", i)
+		}
+		keys[kid] = key
+	}
+	return keys, nil
+}
+
+// LoadJWKSFile reads and parses a JSON Web Key Set document from a file.
+func LoadJWKSFile(path string) (StaticKeys, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("grpc_jwt: failed to read JWKS: %v", err)
+	}
+	return ParseJWKS(data)
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+func (k *jsonWebKey) key() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("RSA exponent too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, nil
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, fmt.Errorf("EC point not on curve %s", k.Crv)
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return nil, err
+		}
+		return secret, nil
+	}
+	return nil, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, fmt.Errorf("missing key parameter")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// RemoteJWKS is a KeySet that fetches a JSON Web Key Set document from a URL.
+//
+// The document is fetched on first use and cached for the refresh interval. Tokens signed with an unknown
+// key ID cause an early refetch, at most once per minute, so that rotated keys are picked up quickly.
+// Concurrent calls share a single fetch, and after a failed fetch no new one is attempted for ten seconds,
+// during which the previously fetched keys stay in use, or the failure is returned if there are none.
+type RemoteJWKS struct {
+	url             string
+	client          *http.Client
+	refreshInterval time.Duration
+
+	mu        sync.Mutex
+	keys      StaticKeys
+	fetchedAt time.Time
+	failedAt  time.Time
+	err       error
+	inFlight  *jwksFetch
+}
+
+// jwksFetch is an in-flight fetch of the document, shared by concurrent calls.
+type jwksFetch struct {
+	done chan struct{}
+}
+
+var (
+	// unknownKidRefetchInterval limits how often tokens with unknown key IDs cause a refetch.
+	unknownKidRefetchInterval = 1 * time.Minute
+	// fetchFailureBackoff is how long no fetch is attempted after a failed one.
+	fetchFailureBackoff = 10 * time.Second
+	// fetchTimeout bounds a fetch, which does not belong to any single call.
+	fetchTimeout = 30 * time.Second
+)
+
+// defaultRefreshInterval is the refresh interval of a RemoteJWKS created without a positive one.
+const defaultRefreshInterval = 1 * time.Hour
+
+// NewRemoteJWKS creates a RemoteJWKS fetching the document from the URL with the client, or
+// http.DefaultClient if nil. The document is refreshed every hour if the refresh interval is not positive.
+func NewRemoteJWKS(url string, client *http.Client, refreshInterval time.Duration) *RemoteJWKS {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if refreshInterval <= 0 {
+		refreshInterval = defaultRefreshInterval
+	}
+	return &RemoteJWKS{url: url, client: client, refreshInterval: refreshInterval}
+}
+
+// Keys implements KeySet.
+func (r *RemoteJWKS) Keys(ctx context.Context, kid string) ([]interface{}, error) {
+	r.mu.Lock()
+	if !r.needsFetch(kid) {
+		keys, err := r.keys, r.err
+		r.mu.Unlock()
+		if keys == nil {
+			return nil, err
+		}
+		return keys.Keys(ctx, kid)
+	}
+	call := r.inFlight
+	if call == nil {
+		call = &jwksFetch{done: make(chan struct{})}
+		r.inFlight = call
+		go r.refresh(call)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("grpc_jwt: failed to fetch JWKS: %v", ctx.Err())
+	}
+	r.mu.Lock()
+	keys, err := r.keys, r.err
+	r.mu.Unlock()
+	// On refresh errors the previously fetched keys stay in use.
+	if keys == nil {
+		return nil, err
+	}
+	return keys.Keys(ctx, kid)
+}
+
+// needsFetch returns whether a call for the key ID must wait for a fetch. It must be called with mu held.
+func (r *RemoteJWKS) needsFetch(kid string) bool {
+	if !r.failedAt.IsZero() && time.Since(r.failedAt) < fetchFailureBackoff {
+		return false
+	}
+	age := time.Since(r.fetchedAt)
+	if r.keys == nil || age >= r.refreshInterval {
+		return true
+	}
+	if kid != "" && age >= unknownKidRefetchInterval {
+		_, known := r.keys[kid]
+		return !known
+	}
+	return false
+}
+
+// refresh runs the fetch, outside of any call so that the calls sharing it may give up independently.
+func (r *RemoteJWKS) refresh(call *jwksFetch) {
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	keys, err := r.fetch(ctx)
+	cancel()
+	r.mu.Lock()
+	if err == nil {
+		r.keys, r.fetchedAt, r.failedAt, r.err = keys, time.Now(), time.Time{}, nil
+	} else {
+		r.failedAt, r.err = time.Now(), err
+	}
+	r.inFlight = nil
+	r.mu.Unlock()
+	close(call.done)
+}
+
+func (r *RemoteJWKS) fetch(ctx context.Context) (StaticKeys, error) {
+	req, err := http.NewRequest(http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("grpc_jwt: failed to fetch JWKS: %v", err)
+	}
+	resp, err := r.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("grpc_jwt: failed to fetch JWKS: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("grpc_jwt: failed to fetch JWKS: %s", resp.Status)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("grpc_jwt: failed to fetch JWKS: %v", err)
+	}
+	return ParseJWKS(data)
+}
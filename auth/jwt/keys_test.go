@@ -0,0 +1,226 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func encodeBigInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func jwksDocument(t *testing.T, ecKid string, ec *ecdsa.PublicKey) []byte {
+	doc := map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa", "use": "sig", "n": encodeBigInt(rsaKey.N), "e": encodeBigInt(big.NewInt(int64(rsaKey.E)))},
+			{"kty": "EC", "kid": ecKid, "crv": "P-256", "x": encodeBigInt(ec.X), "y": encodeBigInt(ec.Y)},
+			{"kty": "oct", "kid": "hmac", "k": base64.RawURLEncoding.EncodeToString(hmacSecret)},
+			{"kty": "RSA", "kid": "encryption", "use": "enc", "n": encodeBigInt(rsaKey.N), "e": "AQAB"},
+			{"kty": "OKP", "kid": "unsupported", "crv": "Ed25519", "x": "AAAA"},
+		},
+	}
+	data, err := json.Marshal(doc)
+	require.NoError(t, err)
+	return data
+}
+
+func TestParseJWKS(t *testing.T) {
+	keys, err := ParseJWKS(jwksDocument(t, "ec", &ecKey.PublicKey))
+	require.NoError(t, err)
+	assert.Len(t, keys, 3, "encryption and unsupported keys must be skipped")
+	assert.Equal(t, &rsaKey.PublicKey, keys["rsa"])
+	assert.Equal(t, hmacSecret, keys["hmac"])
+
+	v := NewVerifier(keys, WithTimeFunc(func() time.Time { return testNow }))
+	for alg, kid := range map[string]string{HS256: "hmac", RS256: "rsa", ES256: "ec"} {
+		_, err := v.Verify(context.TODO(), sign(t, alg, kid, validClaims()))
+		assert.NoError(t, err, "%s token must verify against the JWKS", alg)
+	}
+
+	_, err = ParseJWKS([]byte(`{"keys": [{"kty": "EC", "crv": "P-256", "x": "AQ", "y": "AQ"}]}`))
+	assert.Error(t, err, "points not on the curve must be rejected")
+	_, err = ParseJWKS([]byte(`not json`))
+	assert.Error(t, err)
+}
+
+func TestParseJWKS_WithoutKeyIDs(t *testing.T) {
+	doc := fmt.Sprintf(`{"keys": [{"kty": "RSA", "n": %q, "e": "AQAB"}, {"kty": "EC", "crv": "P-256", "x": %q, "y": %q}]}`,
+		encodeBigInt(rsaKey.N), encodeBigInt(ecKey.X), encodeBigInt(ecKey.Y))
+	keys, err := ParseJWKS([]byte(doc))
+	require.NoError(t, err)
+	assert.Len(t, keys, 2, "keys without a key ID must not overwrite each other")
+
+	v := NewVerifier(keys, WithTimeFunc(func() time.Time { return testNow }))
+	for _, alg := range []string{RS256, ES256} {
+		_, err := v.Verify(context.TODO(), sign(t, alg, "", validClaims()))
+		assert.NoError(t, err, "%s token without key ID must verify against each key without one", alg)
+	}
+}
+
+func TestLoadJWKSFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grpc_jwt")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "jwks.json")
+	require.NoError(t, ioutil.WriteFile(path, jwksDocument(t, "ec", &ecKey.PublicKey), 0600))
+
+	keys, err := LoadJWKSFile(path)
+	require.NoError(t, err)
+	assert.Len(t, keys, 3)
+
+	_, err = LoadJWKSFile(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestRemoteJWKS(t *testing.T) {
+	rotated, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	var fetches int32
+	var document atomic.Value
+	document.Store(jwksDocument(t, "ec", &ecKey.PublicKey))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		w.Write(document.Load().([]byte))
+	}))
+	defer server.Close()
+
+	jwks := NewRemoteJWKS(server.URL, server.Client(), time.Hour)
+	v := NewVerifier(jwks, WithTimeFunc(func() time.Time { return testNow }))
+	for i := 0; i < 3; i++ {
+		_, err := v.Verify(context.TODO(), sign(t, RS256, "rsa", validClaims()))
+		require.NoError(t, err)
+	}
+	assert.EqualValues(t, 1, atomic.LoadInt32(&fetches), "JWKS must be cached")
+
+	// Rotate the EC key. Tokens signed with the new key ID cause a refetch once the rate limit allows.
+	document.Store(jwksDocument(t, "ec-rotated", &rotated.PublicKey))
+	defer func(old time.Duration) { unknownKidRefetchInterval = old }(unknownKidRefetchInterval)
+	unknownKidRefetchInterval = 0
+	keys, err := jwks.Keys(context.TODO(), "ec-rotated")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{&rotated.PublicKey}, keys, "unknown key IDs must cause a refetch")
+	assert.EqualValues(t, 2, atomic.LoadInt32(&fetches))
+}
+
+func TestRemoteJWKS_DefaultRefreshInterval(t *testing.T) {
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		w.Write(jwksDocument(t, "ec", &ecKey.PublicKey))
+	}))
+	defer server.Close()
+
+	jwks := NewRemoteJWKS(server.URL, server.Client(), 0)
+	for i := 0; i < 3; i++ {
+		_, err := jwks.Keys(context.TODO(), "rsa")
+		require.NoError(t, err)
+	}
+	assert.EqualValues(t, 1, atomic.LoadInt32(&fetches), "JWKS must be cached without a refresh interval")
+}
+
+func TestRemoteJWKS_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	v := NewVerifier(NewRemoteJWKS(server.URL, server.Client(), time.Hour), WithTimeFunc(func() time.Time { return testNow }))
+	_, err := v.Verify(context.TODO(), sign(t, RS256, "rsa", validClaims()))
+	assert.Equal(t, codes.Unavailable, status.Code(err), "failures to fetch keys must be reported as unavailable")
+}
+
+func TestRemoteJWKS_FailureBackoff(t *testing.T) {
+	var fetches int32
+	var failing atomic.Value
+	failing.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		if failing.Load().(bool) {
+			http.Error(w, "down", http.StatusInternalServerError)
+			return
+		}
+		w.Write(jwksDocument(t, "ec", &ecKey.PublicKey))
+	}))
+	defer server.Close()
+
+	jwks := NewRemoteJWKS(server.URL, server.Client(), time.Hour)
+	for i := 0; i < 3; i++ {
+		_, err := jwks.Keys(context.TODO(), "rsa")
+		require.Error(t, err)
+	}
+	assert.EqualValues(t, 1, atomic.LoadInt32(&fetches), "failed fetches must not be retried within the backoff")
+
+	failing.Store(false)
+	defer func(old time.Duration) { fetchFailureBackoff = old }(fetchFailureBackoff)
+	fetchFailureBackoff = 0
+	keys, err := jwks.Keys(context.TODO(), "rsa")
+	require.NoError(t, err, "fetch must be retried after the backoff")
+	assert.Equal(t, []interface{}{&rsaKey.PublicKey}, keys)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&fetches))
+}
+
+func TestRemoteJWKS_SharedFetch(t *testing.T) {
+	var fetches int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&fetches, 1) == 1 {
+			w.Write(jwksDocument(t, "ec", &ecKey.PublicKey))
+			return
+		}
+		<-release
+		w.Write(jwksDocument(t, "ec-rotated", &ecKey.PublicKey))
+	}))
+	defer server.Close()
+
+	jwks := NewRemoteJWKS(server.URL, server.Client(), time.Hour)
+	_, err := jwks.Keys(context.TODO(), "rsa")
+	require.NoError(t, err)
+
+	// Tokens with an unknown key ID wait for a single shared refetch, which must not block other calls.
+	defer func(old time.Duration) { unknownKidRefetchInterval = old }(unknownKidRefetchInterval)
+	unknownKidRefetchInterval = 0
+	results := make(chan error, 5)
+	for i := 0; i < cap(results); i++ {
+		go func() {
+			_, err := jwks.Keys(context.TODO(), "ec-rotated")
+			results <- err
+		}()
+	}
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&fetches) == 2 }, time.Second, time.Millisecond)
+	keys, err := jwks.Keys(context.TODO(), "rsa")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{&rsaKey.PublicKey}, keys, "calls for known key IDs must not wait for the fetch")
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	_, err = jwks.Keys(ctx, "ec-rotated")
+	assert.Error(t, err, "calls must stop waiting for the fetch when their context is done")
+
+	close(release)
+	for i := 0; i < cap(results); i++ {
+		assert.NoError(t, <-results)
+	}
+	assert.EqualValues(t, 2, atomic.LoadInt32(&fetches), "concurrent calls must share a single fetch")
+}
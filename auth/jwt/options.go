@@ -0,0 +1,92 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_jwt
+
+import (
+	"time"
+)
+
+var (
+	// DefaultAlgorithms are the signature algorithms accepted unless configured otherwise.
+	DefaultAlgorithms = []string{HS256, RS256, ES256}
+
+	defaultOptions = &options{
+		algorithms: DefaultAlgorithms,
+		scheme:     "bearer",
+		rolesClaim: "roles",
+		timeFunc:   time.Now,
+	}
+)
+
+type options struct {
+	algorithms []string
+	issuers    []string
+	audiences  []string
+	leeway     time.Duration
+	scheme     string
+	rolesClaim string
+	timeFunc   func() time.Time
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+// Option configures the verification of tokens.
+type Option func(*options)
+
+// WithAlgorithms restricts the accepted signature algorithms. Defaults to DefaultAlgorithms.
+func WithAlgorithms(algorithms ...string) Option {
+	return func(o *options) {
+		o.algorithms = algorithms
+	}
+}
+
+// WithIssuer requires the `iss` claim to be one of the issuers.
+func WithIssuer(issuers ...string) Option {
+	return func(o *options) {
+		o.issuers = issuers
+	}
+}
+
+// WithAudience requires the `aud` claim to contain one of the audiences.
+func WithAudience(audiences ...string) Option {
+	return func(o *options) {
+		o.audiences = audiences
+	}
+}
+
+// WithLeeway allows for clock skew between the token issuer and the server when validating `exp` and `nbf`.
+func WithLeeway(leeway time.Duration) Option {
+	return func(o *options) {
+		o.leeway = leeway
+	}
+}
+
+// WithScheme sets the scheme of the `authorization` header expected by AuthFunc. Defaults to `bearer`.
+func WithScheme(scheme string) Option {
+	return func(o *options) {
+		o.scheme = scheme
+	}
+}
+
+// WithRolesClaim sets the claim that AuthFunc takes the roles of the grpc_auth.Principal from, either an array of
+// strings or a space-delimited string. Defaults to `roles`.
+func WithRolesClaim(claim string) Option {
+	return func(o *options) {
+		o.rolesClaim = claim
+	}
+}
+
+// WithTimeFunc sets the clock that `exp` and `nbf` are validated against. Defaults to time.Now.
+func WithTimeFunc(f func() time.Time) Option {
+	return func(o *options) {
+		o.timeFunc = f
+	}
+}
@@ -5,9 +5,10 @@ package grpc_auth
 
 import (
 	"context"
+	"encoding/base64"
 	"strings"
 
-	"github.com/grpc-ecosystem/go-grpc-middleware/util/metautils"
+	"github.com/rkollar/go-grpc-middleware/util/metautils"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -35,3 +36,24 @@ func AuthFromMD(ctx context.Context, expectedScheme string) (string, error) {
 	}
 	return splits[1], nil
 }
+
+// BasicAuthFromMD is a helper function for extracting the credentials of HTTP Basic auth (rfc7617) from the
+// `:authorization` header of the gRPC metadata of the request.
+//
+// If no such authorization is found, the scheme is not `basic`, or the credentials are not a base64 encoded
+// `user:password` pair, an error with gRPC status `Unauthenticated` is returned.
+func BasicAuthFromMD(ctx context.Context) (user string, password string, err error) {
+	encoded, err := AuthFromMD(ctx, "basic")
+	if err != nil {
+		return "", "", err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", status.Errorf(codes.Unauthenticated, "Bad basic auth encoding")
+	}
+	splits := strings.SplitN(string(decoded), ":", 2)
+	if len(splits) < 2 {
+		return "", "", status.Errorf(codes.Unauthenticated, "Bad basic auth credentials")
+	}
+	return splits[0], splits[1], nil
+}
@@ -5,9 +5,10 @@ package grpc_auth
 
 import (
 	"context"
+	"encoding/base64"
 	"testing"
 
-	"github.com/grpc-ecosystem/go-grpc-middleware/util/metautils"
+	"github.com/rkollar/go-grpc-middleware/util/metautils"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -71,3 +72,57 @@ func TestAuthFromMD(t *testing.T) {
 		assert.Equal(t, run.value, out, run.msg)
 	}
 }
+
+func TestBasicAuthFromMD(t *testing.T) {
+	for _, run := range []struct {
+		md       metadata.MD
+		user     string
+		password string
+		errCode  codes.Code
+		msg      string
+	}{
+		{
+			md:       metadata.Pairs("authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte("login:passwd"))),
+			user:     "login",
+			password: "passwd",
+			msg:      "must decode user and password",
+		},
+		{
+			md:       metadata.Pairs("authorization", "basic "+base64.StdEncoding.EncodeToString([]byte("login:pass:wd"))),
+			user:     "login",
+			password: "pass:wd",
+			msg:      "must split at the first colon only",
+		},
+		{
+			md:       metadata.Pairs("authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte("login:"))),
+			user:     "login",
+			password: "",
+			msg:      "must allow empty passwords",
+		},
+		{
+			md:      metadata.Pairs("authorization", "Bearer "+base64.StdEncoding.EncodeToString([]byte("login:passwd"))),
+			errCode: codes.Unauthenticated,
+			msg:     "must check authentication type",
+		},
+		{
+			md:      metadata.Pairs("authorization", "Basic login:passwd"),
+			errCode: codes.Unauthenticated,
+			msg:     "must reject credentials that are not base64 encoded",
+		},
+		{
+			md:      metadata.Pairs("authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte("login"))),
+			errCode: codes.Unauthenticated,
+			msg:     "must reject credentials without a password",
+		},
+	} {
+		ctx := metautils.NiceMD(run.md).ToIncoming(context.TODO())
+		user, password, err := BasicAuthFromMD(ctx)
+		if run.errCode != codes.OK {
+			assert.Equal(t, run.errCode, status.Code(err), run.msg)
+		} else {
+			assert.NoError(t, err, run.msg)
+		}
+		assert.Equal(t, run.user, user, run.msg)
+		assert.Equal(t, run.password, password, run.msg)
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_auth
+
+import (
+	"context"
+	"time"
+)
+
+var (
+	defaultOptions = &options{}
+
+	defaultClientOptions = &clientOptions{
+		refreshBefore: 1 * time.Minute,
+	}
+)
+
+type options struct {
+	methodOverrides map[string]AuthFunc
+	enforceExpiry   bool
+	streamRefresh   StreamRefreshFunc
+}
+
+// Option configures the server-side auth interceptors.
+type Option func(*options)
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+// WithMethodOverrides overrides the AuthFunc for individual methods, keyed by full method name, e.g.
+// `/mwitkow.testproto.TestService/Ping`. Use NoAuth to exempt methods from auth.
+//
+// Method overrides take precedence over ServiceAuthFuncOverride.
+func WithMethodOverrides(overrides map[string]AuthFunc) Option {
+	return func(o *options) {
+		merged := make(map[string]AuthFunc, len(o.methodOverrides)+len(overrides))
+		for method, f := range o.methodOverrides {
+			merged[method] = f
+		}
+		for method, f := range overrides {
+			merged[method] = f
+		}
+		o.methodOverrides = merged
+	}
+}
+
+// WithStreamExpiry enforces the expiry of credentials stored in the context by the AuthFunc, see
+// ContextWithExpiry. Streams are terminated with `Unauthenticated` once their credentials expire, and calls
+// with expired credentials are rejected.
+//
+// On expiry the context of the stream is cancelled and sending or receiving on it fails, so handlers should
+// watch their context. Handlers blocked in RecvMsg are terminated once their next message arrives.
+func WithStreamExpiry() Option {
+	return func(o *options) {
+		o.enforceExpiry = true
+	}
+}
+
+// WithStreamRefresh enforces the expiry of credentials like WithStreamExpiry, and additionally lets the
+// callers of streams extend it by sending refreshed credentials in-band, as inspected by the refresh func.
+func WithStreamRefresh(refresh StreamRefreshFunc) Option {
+	return func(o *options) {
+		o.enforceExpiry = true
+		o.streamRefresh = refresh
+	}
+}
+
+type clientOptions struct {
+	refreshBefore time.Duration
+}
+
+// ClientOption configures the client-side auth interceptors.
+type ClientOption func(*clientOptions)
+
+func evaluateClientOptions(opts []ClientOption) *clientOptions {
+	optCopy := &clientOptions{}
+	*optCopy = *defaultClientOptions
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+// WithRefreshBefore sets how long before its expiry a cached token is refreshed. Defaults to one minute.
+func WithRefreshBefore(d time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.refreshBefore = d
+	}
+}
+
+type certificateOptions struct {
+	commonNames  []string
+	dnsNames     []string
+	spiffeIDs    []string
+	trustDomains []string
+}
+
+// CertificateOption configures the peer identities allowed by CertificateAuthFunc.
+type CertificateOption func(*certificateOptions)
+
+func evaluateCertificateOptions(opts []CertificateOption) *certificateOptions {
+	o := &certificateOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithAllowedCommonNames allows peers whose certificate subject has one of the common names.
+func WithAllowedCommonNames(names ...string) CertificateOption {
+	return func(o *certificateOptions) {
+		o.commonNames = append(o.commonNames, names...)
+	}
+}
+
+// WithAllowedDNSNames allows peers whose certificate has one of the DNS subject alternative names.
+func WithAllowedDNSNames(names ...string) CertificateOption {
+	return func(o *certificateOptions) {
+		o.dnsNames = append(o.dnsNames, names...)
+	}
+}
+
+// WithAllowedSPIFFEIDs allows peers with one of the SPIFFE IDs, e.g. `spiffe://example.org/billing`.
+func WithAllowedSPIFFEIDs(ids ...string) CertificateOption {
+	return func(o *certificateOptions) {
+		o.spiffeIDs = append(o.spiffeIDs, ids...)
+	}
+}
+
+// WithTrustDomains allows peers with a SPIFFE ID in one of the trust domains, e.g. `example.org`.
+func WithTrustDomains(domains ...string) CertificateOption {
+	return func(o *certificateOptions) {
+		o.trustDomains = append(o.trustDomains, domains...)
+	}
+}
+
+var defaultCacheOptions = &cacheOptions{
+	keyFunc:  DefaultCacheKey,
+	size:     1024,
+	ttl:      1 * time.Minute,
+	extract:  principalFromContext,
+	apply:    contextWithPrincipal,
+	timeFunc: time.Now,
+}
+
+type cacheOptions struct {
+	keyFunc     CacheKeyFunc
+	size        int
+	ttl         time.Duration
+	negativeTTL time.Duration
+	extract     CacheResultExtractor
+	apply       CacheResultApplier
+	timeFunc    func() time.Time
+}
+
+// CacheOption configures CachingAuthFunc.
+type CacheOption func(*cacheOptions)
+
+// CacheKeyFunc returns the credential of the call by which CachingAuthFunc caches results. Calls for which it
+// returns false bypass the cache.
+type CacheKeyFunc func(ctx context.Context) (credential string, ok bool)
+
+// CacheResultExtractor returns the result to cache from the context returned by a successful AuthFunc, and
+// when the result expires, or a zero time if it doesn't. Results for which it returns false are not cached.
+type CacheResultExtractor func(authedCtx context.Context) (result interface{}, expiresAt time.Time, ok bool)
+
+// CacheResultApplier stores a cached result in the context of a call, like the cached AuthFunc would.
+type CacheResultApplier func(ctx context.Context, result interface{}) context.Context
+
+func evaluateCacheOptions(opts []CacheOption) *cacheOptions {
+	optCopy := &cacheOptions{}
+	*optCopy = *defaultCacheOptions
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+// WithCacheKeyFunc sets the function returning the credential of a call. Defaults to DefaultCacheKey.
+func WithCacheKeyFunc(f CacheKeyFunc) CacheOption {
+	return func(o *cacheOptions) {
+		o.keyFunc = f
+	}
+}
+
+// WithCacheSize sets the maximum number of cached results, beyond which the least recently used ones are
+// evicted. Defaults to 1024.
+func WithCacheSize(size int) CacheOption {
+	return func(o *cacheOptions) {
+		o.size = size
+	}
+}
+
+// WithCacheTTL sets how long successful results are cached at most. Defaults to one minute.
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(o *cacheOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithNegativeCacheTTL sets how long `Unauthenticated` and `PermissionDenied` failures are cached. Other
+// failures are never cached. Defaults to zero, which disables caching of failures.
+func WithNegativeCacheTTL(ttl time.Duration) CacheOption {
+	return func(o *cacheOptions) {
+		o.negativeTTL = ttl
+	}
+}
+
+// WithCacheResult sets how results are extracted from the contexts returned by the AuthFunc, and stored in
+// the contexts of calls served from the cache. Defaults to the Principal and the expiry of the context.
+func WithCacheResult(extract CacheResultExtractor, apply CacheResultApplier) CacheOption {
+	return func(o *cacheOptions) {
+		o.extract = extract
+		o.apply = apply
+	}
+}
+
+// WithCacheTimeFunc sets the clock used for expiring cached results. Defaults to time.Now.
+func WithCacheTimeFunc(timeFunc func() time.Time) CacheOption {
+	return func(o *cacheOptions) {
+		o.timeFunc = timeFunc
+	}
+}
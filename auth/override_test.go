@@ -0,0 +1,113 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_auth_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	loginMethod = "/mwitkow.testproto.TestService/Login"
+	adminMethod = "/mwitkow.testproto.TestService/Admin"
+)
+
+func rejectingAuthFunc(code codes.Code, message string) grpc_auth.AuthFunc {
+	return func(ctx context.Context) (context.Context, error) {
+		return nil, status.Errorf(code, message)
+	}
+}
+
+func markingAuthFunc(marker string) grpc_auth.AuthFunc {
+	return func(ctx context.Context) (context.Context, error) {
+		return context.WithValue(ctx, authedMarker, marker), nil
+	}
+}
+
+type overridingService struct{}
+
+func (s *overridingService) AuthFuncOverride(ctx context.Context, fullMethodName string) (context.Context, error) {
+	return context.WithValue(ctx, authedMarker, "service"), nil
+}
+
+func TestUnaryServerInterceptor_MethodOverrides(t *testing.T) {
+	interceptor := grpc_auth.UnaryServerInterceptor(
+		rejectingAuthFunc(codes.Unauthenticated, "global"),
+		grpc_auth.WithMethodOverrides(map[string]grpc_auth.AuthFunc{loginMethod: grpc_auth.NoAuth}),
+		grpc_auth.WithMethodOverrides(map[string]grpc_auth.AuthFunc{adminMethod: markingAuthFunc("admin")}),
+	)
+	call := func(srv interface{}, fullMethod string) (interface{}, error) {
+		return interceptor(context.TODO(), nil, &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return ctx.Value(authedMarker), nil
+		})
+	}
+
+	_, err := call(nil, pingMethod)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "global AuthFunc must apply to methods without an override")
+	_, err = call(nil, loginMethod)
+	assert.NoError(t, err, "NoAuth must exempt the method from auth")
+	marker, err := call(nil, adminMethod)
+	require.NoError(t, err)
+	assert.Equal(t, "admin", marker, "overrides must be merged across options")
+
+	marker, err = call(&overridingService{}, pingMethod)
+	require.NoError(t, err)
+	assert.Equal(t, "service", marker, "service override must apply to methods without an override")
+	marker, err = call(&overridingService{}, adminMethod)
+	require.NoError(t, err)
+	assert.Equal(t, "admin", marker, "method overrides must take precedence over the service override")
+}
+
+func TestStreamServerInterceptor_MethodOverrides(t *testing.T) {
+	interceptor := grpc_auth.StreamServerInterceptor(
+		rejectingAuthFunc(codes.Unauthenticated, "global"),
+		grpc_auth.WithMethodOverrides(map[string]grpc_auth.AuthFunc{loginMethod: markingAuthFunc("login")}),
+	)
+	var marker interface{}
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		marker = stream.Context().Value(authedMarker)
+		return nil
+	}
+	stream := &contextServerStream{ctx: context.TODO()}
+
+	err := interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: pingMethod}, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	require.NoError(t, interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: loginMethod}, handler))
+	assert.Equal(t, "login", marker, "method override context must be passed to the handler")
+}
+
+func TestAnyOf(t *testing.T) {
+	authFunc := grpc_auth.AnyOf(
+		rejectingAuthFunc(codes.Unauthenticated, "no client certificate"),
+		markingAuthFunc("bearer"),
+		rejectingAuthFunc(codes.Internal, "must not be called"),
+	)
+	ctx, err := authFunc(context.TODO())
+	require.NoError(t, err, "first success must be accepted")
+	assert.Equal(t, "bearer", ctx.Value(authedMarker))
+
+	authFunc = grpc_auth.AnyOf(
+		rejectingAuthFunc(codes.Unauthenticated, "no client certificate"),
+		rejectingAuthFunc(codes.PermissionDenied, "token expired"),
+	)
+	_, err = authFunc(context.TODO())
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "failures must be aggregated into Unauthenticated")
+	assert.Equal(t, "no authentication method succeeded: no client certificate; token expired", status.Convert(err).Message())
+}
+
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
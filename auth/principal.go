@@ -0,0 +1,36 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_auth
+
+import (
+	"context"
+)
+
+// Principal is an authenticated caller, as established by an AuthFunc.
+//
+// AuthFuncs store the principal in the context with ContextWithPrincipal, so that authorization and other
+// middleware can act on the caller independently of how it was authenticated.
+type Principal struct {
+	// Name identifies the caller, e.g. a user ID, a SPIFFE ID or the name of an API key.
+	Name string `json:"name"`
+	// Roles are the roles granted to the caller.
+	Roles []string `json:"roles,omitempty"`
+	// Scopes are the scopes granted to the caller, e.g. OAuth2 scopes of its token.
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+type principalMarker struct{}
+
+var principalMarkerKey = &principalMarker{}
+
+// ContextWithPrincipal returns a copy of the context carrying the principal.
+func ContextWithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalMarkerKey, principal)
+}
+
+// PrincipalFromContext returns the principal stored in the context by an AuthFunc.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalMarkerKey).(*Principal)
+	return principal, ok && principal != nil
+}
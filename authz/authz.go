@@ -0,0 +1,82 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_authz
+
+import (
+	"context"
+
+	"github.com/rkollar/go-grpc-middleware"
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Decision describes a call denied by the policy.
+type Decision struct {
+	// FullMethod is the full method name of the call.
+	FullMethod string
+	// Principal is the principal of the call, nil if unauthenticated.
+	Principal *grpc_auth.Principal
+	// Rule is the method pattern of the rule that denied the call, empty if it was denied by default.
+	Rule string
+	// Err is the error that the call fails with, unless DryRun is set.
+	Err error
+	// DryRun is set if the call was let through nevertheless.
+	DryRun bool
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor that authorizes calls against the policy.
+func UnaryServerInterceptor(policy *Policy, opts ...Option) grpc.UnaryServerInterceptor {
+	o := evaluateOptions(opts)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := authorize(ctx, policy, o, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a new streaming server interceptor that authorizes calls against the policy.
+func StreamServerInterceptor(policy *Policy, opts ...Option) grpc.StreamServerInterceptor {
+	o := evaluateOptions(opts)
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authorize(stream.Context(), policy, o, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+// Middleware returns a new grpc_middleware.Middleware that authorizes calls against the policy.
+//
+// It must run after the authentication middleware establishing the principal, e.g. grpc_auth.
+func Middleware(policy *Policy, opts ...Option) grpc_middleware.Middleware {
+	return grpc_middleware.Middleware{
+		UnaryServer:  UnaryServerInterceptor(policy, opts...),
+		StreamServer: StreamServerInterceptor(policy, opts...),
+	}
+}
+
+func authorize(ctx context.Context, policy *Policy, o *options, fullMethod string) error {
+	principal, ok := o.principalFunc(ctx)
+	if !ok {
+		principal = nil
+	}
+	decision := Decision{FullMethod: fullMethod, Principal: principal, DryRun: o.dryRun}
+	if rule := policy.RuleFor(fullMethod); rule != nil {
+		decision.Rule = rule.Method
+		decision.Err = rule.Evaluate(principal)
+	} else if o.denyByDefault {
+		decision.Err = status.Errorf(codes.PermissionDenied, "permission denied")
+	}
+	if decision.Err == nil {
+		return nil
+	}
+	o.logger(ctx, decision)
+	if o.dryRun {
+		return nil
+	}
+	return decision.Err
+}
@@ -0,0 +1,176 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_authz
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/rkollar/go-grpc-middleware"
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"github.com/rkollar/go-grpc-middleware/auth/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func unaryCall(t *testing.T, interceptor grpc.UnaryServerInterceptor, ctx context.Context, fullMethod string) (bool, error) {
+	handled := false
+	_, err := interceptor(ctx, "request", &grpc.UnaryServerInfo{FullMethod: fullMethod}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		handled = true
+		return "response", nil
+	})
+	return handled, err
+}
+
+func testPolicy(t *testing.T) *Policy {
+	policy, err := NewPolicy(
+		Rule{Method: "/mwitkow.testproto.TestService/*", Roles: []string{"user"}},
+		Rule{Method: healthMethod, AllowUnauthenticated: true},
+	)
+	require.NoError(t, err)
+	return policy
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	var decisions []Decision
+	interceptor := UnaryServerInterceptor(testPolicy(t), WithDecisionLogger(func(ctx context.Context, d Decision) {
+		decisions = append(decisions, d)
+	}))
+	user := grpc_auth.ContextWithPrincipal(context.TODO(), &grpc_auth.Principal{Name: "alice", Roles: []string{"user"}})
+	guest := grpc_auth.ContextWithPrincipal(context.TODO(), &grpc_auth.Principal{Name: "bob", Roles: []string{"guest"}})
+
+	handled, err := unaryCall(t, interceptor, user, pingMethod)
+	require.NoError(t, err)
+	assert.True(t, handled, "allowed calls must be handled")
+
+	handled, err = unaryCall(t, interceptor, guest, pingMethod)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "principals without the role must be denied")
+	assert.False(t, handled, "denied calls must not be handled")
+
+	handled, err = unaryCall(t, interceptor, context.TODO(), pingMethod)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "unauthenticated calls must be denied")
+	assert.False(t, handled)
+
+	handled, err = unaryCall(t, interceptor, context.TODO(), otherMethod)
+	require.NoError(t, err, "methods without a rule must be allowed by default")
+	assert.True(t, handled)
+
+	require.Len(t, decisions, 2, "denied calls must be logged")
+	assert.Equal(t, Decision{
+		FullMethod: pingMethod,
+		Principal:  &grpc_auth.Principal{Name: "bob", Roles: []string{"guest"}},
+		Rule:       "/mwitkow.testproto.TestService/*",
+		Err:        status.Errorf(codes.PermissionDenied, "permission denied"),
+	}, decisions[0])
+}
+
+func TestUnaryServerInterceptor_DenyByDefault(t *testing.T) {
+	interceptor := UnaryServerInterceptor(testPolicy(t), WithDenyByDefault(), WithDecisionLogger(func(context.Context, Decision) {}))
+	admin := grpc_auth.ContextWithPrincipal(context.TODO(), &grpc_auth.Principal{Name: "root", Roles: []string{"admin"}})
+	handled, err := unaryCall(t, interceptor, admin, otherMethod)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "methods without a rule must be denied")
+	assert.False(t, handled)
+
+	handled, err = unaryCall(t, interceptor, context.TODO(), healthMethod)
+	require.NoError(t, err, "methods with a rule must still be evaluated")
+	assert.True(t, handled)
+}
+
+func TestUnaryServerInterceptor_DryRun(t *testing.T) {
+	var decisions []Decision
+	interceptor := UnaryServerInterceptor(testPolicy(t), WithDenyByDefault(), WithDryRun(), WithDecisionLogger(func(ctx context.Context, d Decision) {
+		decisions = append(decisions, d)
+	}))
+	handled, err := unaryCall(t, interceptor, context.TODO(), pingMethod)
+	require.NoError(t, err, "dry run must not fail calls")
+	assert.True(t, handled, "dry run must let denied calls through")
+	handled, err = unaryCall(t, interceptor, context.TODO(), otherMethod)
+	require.NoError(t, err, "dry run must not fail calls")
+	assert.True(t, handled)
+
+	require.Len(t, decisions, 2, "dry run must log denied calls")
+	assert.True(t, decisions[0].DryRun)
+	assert.Equal(t, codes.Unauthenticated, status.Code(decisions[0].Err))
+	assert.Equal(t, "", decisions[1].Rule, "calls denied by default must not have a rule")
+}
+
+func TestUnaryServerInterceptor_PrincipalFunc(t *testing.T) {
+	interceptor := UnaryServerInterceptor(testPolicy(t), WithPrincipalFunc(func(ctx context.Context) (*grpc_auth.Principal, bool) {
+		return &grpc_auth.Principal{Name: "mapped", Roles: []string{"user"}}, true
+	}))
+	handled, err := unaryCall(t, interceptor, context.TODO(), pingMethod)
+	require.NoError(t, err, "principal func must be used")
+	assert.True(t, handled)
+}
+
+func signHS256(t *testing.T, secret []byte, claims map[string]interface{}) string {
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := encode(map[string]string{"alg": grpc_jwt.HS256, "typ": "JWT"}) + "." + encode(claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestUnaryServerInterceptor_JWT(t *testing.T) {
+	secret := []byte("some_secret")
+	interceptor := grpc_middleware.ChainUnaryServer(
+		grpc_auth.UnaryServerInterceptor(grpc_jwt.AuthFunc(grpc_jwt.StaticKeys{"hmac": secret})),
+		UnaryServerInterceptor(testPolicy(t), WithDecisionLogger(func(context.Context, Decision) {})),
+	)
+	callWithToken := func(roles ...string) (bool, error) {
+		token := signHS256(t, secret, map[string]interface{}{
+			"sub":   "alice",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"roles": roles,
+		})
+		ctx := metadata.NewIncomingContext(context.TODO(), metadata.Pairs("authorization", "Bearer "+token))
+		return unaryCall(t, interceptor, ctx, pingMethod)
+	}
+
+	handled, err := callWithToken("user")
+	require.NoError(t, err, "callers with the role in their token must be allowed")
+	assert.True(t, handled)
+
+	handled, err = callWithToken("guest")
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "callers without the role in their token must be denied")
+	assert.False(t, handled)
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (f *fakeServerStream) Context() context.Context {
+	return f.ctx
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	interceptor := StreamServerInterceptor(testPolicy(t), WithDecisionLogger(func(context.Context, Decision) {}))
+	handled := 0
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		handled++
+		return nil
+	}
+	info := &grpc.StreamServerInfo{FullMethod: listMethod, IsServerStream: true}
+	user := grpc_auth.ContextWithPrincipal(context.TODO(), &grpc_auth.Principal{Name: "alice", Roles: []string{"user"}})
+
+	require.NoError(t, interceptor(nil, &fakeServerStream{ctx: user}, info, handler))
+	err := interceptor(nil, &fakeServerStream{ctx: context.TODO()}, info, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "unauthenticated streams must be denied")
+	assert.Equal(t, 1, handled, "only allowed streams must be handled")
+}
@@ -0,0 +1,32 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`grpc_authz` is a declarative, role-based authorization middleware for gRPC.
+
+Server Side Authorization Middleware
+
+While `grpc_auth` establishes who the caller is, `grpc_authz` decides what the caller may do. A `Policy`
+maps full method names, service wildcards (`/pkg.Service/*`) or all methods (`*`) to the roles and
+scopes required to call them. Policies are built in code with `NewPolicy`, or loaded from JSON or YAML
+documents:
+
+	rules:
+	  - method: "*"
+	    roles: [admin]
+	  - method: /mwitkow.testproto.TestService/*
+	    roles: [user, admin]
+	    scopes: [ping]
+	  - method: /grpc.health.v1.Health/Check
+	    allow_unauthenticated: true
+
+Calls are evaluated against the `grpc_auth.Principal` stored in the context by the `AuthFunc`, so the
+authorization middleware must run after the authentication one. Use `WithPrincipalFunc` to derive the
+principal differently, e.g. from custom claims of a `grpc_jwt` token.
+
+Calls to methods without a rule are allowed, unless `WithDenyByDefault` is set. To roll out a new policy
+safely, `WithDryRun` only logs the calls that would be denied instead of failing them.
+
+Please see examples for simple examples of use.
+*/
+package grpc_authz
@@ -0,0 +1,46 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_authz_test
+
+import (
+	"context"
+
+	"github.com/rkollar/go-grpc-middleware"
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"github.com/rkollar/go-grpc-middleware/authz"
+	"google.golang.org/grpc"
+)
+
+var authFunc grpc_auth.AuthFunc
+
+// Simple example of server initialization code, authorizing calls after authenticating them.
+func Example_serverConfig() {
+	policy, err := grpc_authz.LoadFile("/etc/myservice/policy.yaml")
+	if err != nil {
+		panic(err)
+	}
+	_ = grpc.NewServer(grpc_middleware.ServerOptions(
+		grpc_auth.Middleware(authFunc),
+		grpc_authz.Middleware(policy, grpc_authz.WithDenyByDefault()),
+	)...)
+}
+
+// Simple example of a policy built in code, rolled out in dry run mode.
+func ExampleNewPolicy() {
+	policy, err := grpc_authz.NewPolicy(
+		grpc_authz.Rule{Method: grpc_authz.AllMethods, Roles: []string{"admin"}},
+		grpc_authz.Rule{Method: "/mwitkow.testproto.TestService/*", Roles: []string{"user"}, Scopes: []string{"ping"}},
+		grpc_authz.Rule{Method: "/grpc.health.v1.Health/Check", AllowUnauthenticated: true},
+	)
+	if err != nil {
+		panic(err)
+	}
+	_ = grpc.NewServer(
+		grpc.UnaryInterceptor(grpc_authz.UnaryServerInterceptor(policy, grpc_authz.WithDryRun(), grpc_authz.WithDecisionLogger(
+			func(ctx context.Context, d grpc_authz.Decision) {
+				// Report d to your logging or metrics system.
+			},
+		))),
+	)
+}
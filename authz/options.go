@@ -0,0 +1,85 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_authz
+
+import (
+	"context"
+
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"google.golang.org/grpc/grpclog"
+)
+
+var (
+	defaultOptions = &options{
+		principalFunc: grpc_auth.PrincipalFromContext,
+		logger:        logDecision,
+	}
+)
+
+type options struct {
+	denyByDefault bool
+	dryRun        bool
+	principalFunc PrincipalFunc
+	logger        DecisionLogger
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+// Option configures the authorization interceptors.
+type Option func(*options)
+
+// PrincipalFunc returns the principal of the call, as established by an AuthFunc, and false for unauthenticated calls.
+type PrincipalFunc func(ctx context.Context) (*grpc_auth.Principal, bool)
+
+// DecisionLogger is called for every call denied by the policy.
+type DecisionLogger func(ctx context.Context, decision Decision)
+
+// WithDenyByDefault denies calls to methods that no rule of the policy matches. By default they are allowed.
+func WithDenyByDefault() Option {
+	return func(o *options) {
+		o.denyByDefault = true
+	}
+}
+
+// WithDryRun lets calls denied by the policy through, only logging the decisions. Use it to roll out a policy
+// without breaking existing callers.
+func WithDryRun() Option {
+	return func(o *options) {
+		o.dryRun = true
+	}
+}
+
+// WithPrincipalFunc sets how the principal is obtained, e.g. to map the claims of a JWT to roles and scopes.
+// Defaults to grpc_auth.PrincipalFromContext.
+func WithPrincipalFunc(f PrincipalFunc) Option {
+	return func(o *options) {
+		o.principalFunc = f
+	}
+}
+
+// WithDecisionLogger sets the logger of denied calls. Defaults to logging a warning with grpclog.
+func WithDecisionLogger(logger DecisionLogger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+func logDecision(_ context.Context, d Decision) {
+	name := "<unauthenticated>"
+	if d.Principal != nil {
+		name = d.Principal.Name
+	}
+	prefix := "grpc_authz: denied"
+	if d.DryRun {
+		prefix = "grpc_authz: dry run, would deny"
+	}
+	grpclog.Warningf("%s call to %s by %s (rule %q): %v", prefix, d.FullMethod, name, d.Rule, d.Err)
+}
@@ -0,0 +1,164 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_authz
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v2"
+)
+
+// AllMethods is the method pattern of a rule that applies to all methods.
+const AllMethods = "*"
+
+// Rule grants access to the methods matching its method pattern.
+type Rule struct {
+	// Method is a full method name (`/pkg.Service/Method`), a service wildcard (`/pkg.Service/*`) or AllMethods.
+	Method string `json:"method" yaml:"method"`
+	// Roles grants access to principals with any of the roles.
+	Roles []string `json:"roles,omitempty" yaml:"roles,omitempty"`
+	// Scopes grants access to principals with all of the scopes.
+	Scopes []string `json:"scopes,omitempty" yaml:"scopes,omitempty"`
+	// AllowUnauthenticated grants access to all callers, including ones without a principal.
+	AllowUnauthenticated bool `json:"allow_unauthenticated,omitempty" yaml:"allow_unauthenticated,omitempty"`
+}
+
+// Policy maps methods to the rules granting access to them.
+//
+// Each call is evaluated against the most specific rule matching its method: a rule for the full method
+// name takes precedence over a service wildcard, which takes precedence over AllMethods. A rule requiring
+// neither roles nor scopes grants access to any authenticated principal, while a rule requiring both
+// grants access to principals with any of the roles and all of the scopes.
+type Policy struct {
+	methods  map[string]*Rule
+	services map[string]*Rule
+	fallback *Rule
+}
+
+// NewPolicy creates a Policy out of the rules. Each method pattern may only be used by a single rule.
+func NewPolicy(rules ...Rule) (*Policy, error) {
+	p := &Policy{methods: map[string]*Rule{}, services: map[string]*Rule{}}
+	for i := range rules {
+		rule := rules[i]
+		var target map[string]*Rule
+		key := rule.Method
+		switch {
+		case rule.Method == AllMethods:
+			if p.fallback != nil {
+				return nil, fmt.Errorf("grpc_authz: duplicate rule for method %q", rule.Method)
+			}
+			p.fallback = &rule
+			continue
+		case strings.HasSuffix(rule.Method, "/*"):
+			target, key = p.services, strings.TrimSuffix(rule.Method, "*")
+		default:
+			target = p.methods
+		}
+		if !strings.HasPrefix(key, "/") || strings.Count(key, "/") != 2 || strings.Contains(key, "*") || strings.HasPrefix(key, "//") {
+			return nil, fmt.Errorf("grpc_authz: invalid method pattern %q", rule.Method)
+		}
+		if _, ok := target[key]; ok {
+			return nil, fmt.Errorf("grpc_authz: duplicate rule for method %q", rule.Method)
+		}
+		target[key] = &rule
+	}
+	return p, nil
+}
+
+type policyDocument struct {
+	Rules []Rule `json:"rules" yaml:"rules"`
+}
+
+// ParseJSON parses a Policy from a JSON document of the form `{"rules": [{"method": "*", "roles": ["admin"]}]}`.
+//
+// Unknown fields are rejected, so that a misspelled constraint does not silently grant access.
+func ParseJSON(data []byte) (*Policy, error) {
+	var doc policyDocument
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("grpc_authz: malformed policy: %v", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("grpc_authz: malformed policy: unexpected data after the document")
+	}
+	return NewPolicy(doc.Rules...)
+}
+
+// ParseYAML parses a Policy from a YAML document with the same structure as the one of ParseJSON.
+func ParseYAML(data []byte) (*Policy, error) {
+	var doc policyDocument
+	if err := yaml.UnmarshalStrict(data, &doc); err != nil {
+		return nil, fmt.Errorf("grpc_authz: malformed policy: %v", err)
+	}
+	return NewPolicy(doc.Rules...)
+}
+
+// LoadFile reads a Policy from a file, parsed as YAML if its extension is `.yaml` or `.yml` and as JSON otherwise.
+func LoadFile(path string) (*Policy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("grpc_authz: failed to read policy: %v", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return ParseYAML(data)
+	}
+	return ParseJSON(data)
+}
+
+// RuleFor returns the most specific rule matching the method, or nil if there is none.
+func (p *Policy) RuleFor(fullMethod string) *Rule {
+	if rule, ok := p.methods[fullMethod]; ok {
+		return rule
+	}
+	if i := strings.LastIndex(fullMethod, "/"); i > 0 {
+		if rule, ok := p.services[fullMethod[:i+1]]; ok {
+			return rule
+		}
+	}
+	return p.fallback
+}
+
+// Evaluate checks whether the rule grants access to the principal, which is nil for unauthenticated calls.
+//
+// It returns an error with the gRPC status `Unauthenticated` if the rule requires a principal and there is
+// none, and `PermissionDenied` if the principal lacks the required roles or scopes.
+func (r *Rule) Evaluate(principal *grpc_auth.Principal) error {
+	if r.AllowUnauthenticated {
+		return nil
+	}
+	if principal == nil {
+		return status.Errorf(codes.Unauthenticated, "authentication required")
+	}
+	if len(r.Roles) > 0 && !containsAny(principal.Roles, r.Roles) {
+		return status.Errorf(codes.PermissionDenied, "permission denied")
+	}
+	for _, scope := range r.Scopes {
+		if !containsAny(principal.Scopes, []string{scope}) {
+			return status.Errorf(codes.PermissionDenied, "permission denied")
+		}
+	}
+	return nil
+}
+
+func containsAny(have []string, want []string) bool {
+	for _, h := range have {
+		for _, w := range want {
+			if h == w {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_authz
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	pingMethod   = "/mwitkow.testproto.TestService/Ping"
+	listMethod   = "/mwitkow.testproto.TestService/PingList"
+	healthMethod = "/grpc.health.v1.Health/Check"
+	otherMethod  = "/other.Service/Get"
+)
+
+const yamlPolicy = `
+rules:
+  - method: "*"
+    roles: [admin]
+  - method: /mwitkow.testproto.TestService/*
+    roles: [user, admin]
+    scopes: [ping]
+  - method: /mwitkow.testproto.TestService/PingList
+    roles: [lister]
+  - method: /grpc.health.v1.Health/Check
+    allow_unauthenticated: true
+`
+
+const jsonPolicy = `{"rules": [
+	{"method": "*", "roles": ["admin"]},
+	{"method": "/mwitkow.testproto.TestService/*", "roles": ["user", "admin"], "scopes": ["ping"]},
+	{"method": "/mwitkow.testproto.TestService/PingList", "roles": ["lister"]},
+	{"method": "/grpc.health.v1.Health/Check", "allow_unauthenticated": true}
+]}`
+
+func TestPolicy_RuleFor(t *testing.T) {
+	policy, err := ParseYAML([]byte(yamlPolicy))
+	require.NoError(t, err)
+	assert.Equal(t, "/grpc.health.v1.Health/Check", policy.RuleFor(healthMethod).Method, "exact rules must match")
+	assert.Equal(t, "/mwitkow.testproto.TestService/PingList", policy.RuleFor(listMethod).Method, "exact rules must take precedence over wildcards")
+	assert.Equal(t, "/mwitkow.testproto.TestService/*", policy.RuleFor(pingMethod).Method, "service wildcards must match")
+	assert.Equal(t, "*", policy.RuleFor(otherMethod).Method, "all methods rule must match everything else")
+
+	policy, err = NewPolicy(Rule{Method: "/grpc.health.v1.Health/*"})
+	require.NoError(t, err)
+	assert.Nil(t, policy.RuleFor(pingMethod), "methods without a rule must not match")
+	assert.Nil(t, policy.RuleFor("/grpc.health.v1.HealthCheck/Check"), "wildcards must not match services sharing a prefix")
+}
+
+func TestRule_Evaluate(t *testing.T) {
+	policy, err := ParseJSON([]byte(jsonPolicy))
+	require.NoError(t, err)
+	for _, tcase := range []struct {
+		name      string
+		method    string
+		principal *grpc_auth.Principal
+		code      codes.Code
+	}{
+		{"unauthenticated", pingMethod, nil, codes.Unauthenticated},
+		{"allowed unauthenticated", healthMethod, nil, codes.OK},
+		{"role and scope", pingMethod, &grpc_auth.Principal{Name: "alice", Roles: []string{"user"}, Scopes: []string{"ping", "other"}}, codes.OK},
+		{"missing scope", pingMethod, &grpc_auth.Principal{Name: "alice", Roles: []string{"user"}}, codes.PermissionDenied},
+		{"missing role", pingMethod, &grpc_auth.Principal{Name: "bob", Roles: []string{"guest"}, Scopes: []string{"ping"}}, codes.PermissionDenied},
+		{"exact rule", listMethod, &grpc_auth.Principal{Name: "carol", Roles: []string{"lister"}}, codes.OK},
+		{"fallback rule", otherMethod, &grpc_auth.Principal{Name: "root", Roles: []string{"admin"}}, codes.OK},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			err := policy.RuleFor(tcase.method).Evaluate(tcase.principal)
+			assert.Equal(t, tcase.code, status.Code(err))
+		})
+	}
+}
+
+func TestNewPolicy_Errors(t *testing.T) {
+	for _, rules := range [][]Rule{
+		{{Method: "*"}, {Method: "*"}},
+		{{Method: pingMethod}, {Method: pingMethod}},
+		{{Method: "/svc/*"}, {Method: "/svc/*"}},
+		{{Method: "mwitkow.testproto.TestService/Ping"}},
+		{{Method: "/mwitkow.testproto.TestService"}},
+		{{Method: "/mwitkow.testproto.*/Ping"}},
+		{{Method: ""}},
+	} {
+		_, err := NewPolicy(rules...)
+		assert.Error(t, err, "%v must be rejected", rules)
+	}
+	_, err := ParseYAML([]byte("rules:\n  - method: \"*\"\n    role: [admin]\n"))
+	assert.Error(t, err, "unknown YAML fields must be rejected")
+	_, err = ParseJSON([]byte(`{"rules": [{"method": "*", "role": ["admin"]}]}`))
+	assert.Error(t, err, "unknown JSON fields must be rejected")
+	_, err = ParseJSON([]byte(`{"rules": []} {"rules": []}`))
+	assert.Error(t, err, "trailing JSON data must be rejected")
+	_, err = ParseJSON([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestLoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grpc_authz")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{"policy.yaml": yamlPolicy, "policy.json": jsonPolicy} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+		policy, err := LoadFile(path)
+		require.NoError(t, err, "%s must load", name)
+		assert.Equal(t, []string{"lister"}, policy.RuleFor(listMethod).Roles, "%s must be parsed", name)
+	}
+	_, err = LoadFile(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+}
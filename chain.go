@@ -16,22 +16,33 @@ import (
 // Execution is done in left-to-right order, including passing of context.
 // For example ChainUnaryServer(one, two, three) will execute one before two before three, and three
 // will see context changes of one and two.
+//
+// The chain is built once, on creation. A chain of a single interceptor returns it unchanged, and
+// longer chains only allocate a handler for an interceptor once the previous one calls into it. Handlers
+// stay valid after the interceptor returns, e.g. for interceptors that run them on another goroutine.
 func ChainUnaryServer(interceptors ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
-	n := len(interceptors)
-
-	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		chainer := func(currentInter grpc.UnaryServerInterceptor, currentHandler grpc.UnaryHandler) grpc.UnaryHandler {
-			return func(currentCtx context.Context, currentReq interface{}) (interface{}, error) {
-				return currentInter(currentCtx, currentReq, info, currentHandler)
-			}
-		}
-
-		chainedHandler := handler
-		for i := n - 1; i >= 0; i-- {
-			chainedHandler = chainer(interceptors[i], chainedHandler)
+	switch len(interceptors) {
+	case 0:
+		return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			return handler(ctx, req)
 		}
+	case 1:
+		return interceptors[0]
+	}
+	chain := append([]grpc.UnaryServerInterceptor(nil), interceptors...)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return chain[0](ctx, req, info, chainUnaryServerHandler(chain, 0, info, handler))
+	}
+}
 
-		return chainedHandler(ctx, req)
+// chainUnaryServerHandler returns the handler passed to the interceptor at index curr, which calls
+// the next interceptor in the chain, or the final handler if curr is the last one.
+func chainUnaryServerHandler(chain []grpc.UnaryServerInterceptor, curr int, info *grpc.UnaryServerInfo, finalHandler grpc.UnaryHandler) grpc.UnaryHandler {
+	if curr == len(chain)-1 {
+		return finalHandler
+	}
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		return chain[curr+1](ctx, req, info, chainUnaryServerHandler(chain, curr+1, info, finalHandler))
 	}
 }
 
@@ -41,21 +52,28 @@ func ChainUnaryServer(interceptors ...grpc.UnaryServerInterceptor) grpc.UnarySer
 // For example ChainUnaryServer(one, two, three) will execute one before two before three.
 // If you want to pass context between interceptors, use WrapServerStream.
 func ChainStreamServer(interceptors ...grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
-	n := len(interceptors)
-
-	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		chainer := func(currentInter grpc.StreamServerInterceptor, currentHandler grpc.StreamHandler) grpc.StreamHandler {
-			return func(currentSrv interface{}, currentStream grpc.ServerStream) error {
-				return currentInter(currentSrv, currentStream, info, currentHandler)
-			}
-		}
-
-		chainedHandler := handler
-		for i := n - 1; i >= 0; i-- {
-			chainedHandler = chainer(interceptors[i], chainedHandler)
+	switch len(interceptors) {
+	case 0:
+		return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			return handler(srv, ss)
 		}
+	case 1:
+		return interceptors[0]
+	}
+	chain := append([]grpc.StreamServerInterceptor(nil), interceptors...)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return chain[0](srv, ss, info, chainStreamServerHandler(chain, 0, info, handler))
+	}
+}
 
-		return chainedHandler(srv, ss)
+// chainStreamServerHandler returns the handler passed to the interceptor at index curr, which calls
+// the next interceptor in the chain, or the final handler if curr is the last one.
+func chainStreamServerHandler(chain []grpc.StreamServerInterceptor, curr int, info *grpc.StreamServerInfo, finalHandler grpc.StreamHandler) grpc.StreamHandler {
+	if curr == len(chain)-1 {
+		return finalHandler
+	}
+	return func(srv interface{}, ss grpc.ServerStream) error {
+		return chain[curr+1](srv, ss, info, chainStreamServerHandler(chain, curr+1, info, finalHandler))
 	}
 }
 
@@ -63,22 +81,31 @@ func ChainStreamServer(interceptors ...grpc.StreamServerInterceptor) grpc.Stream
 //
 // Execution is done in left-to-right order, including passing of context.
 // For example ChainUnaryClient(one, two, three) will execute one before two before three.
+//
+// Like ChainUnaryServer, the chain is built once and invokers are only allocated as the call reaches them.
 func ChainUnaryClient(interceptors ...grpc.UnaryClientInterceptor) grpc.UnaryClientInterceptor {
-	n := len(interceptors)
-
-	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
-		chainer := func(currentInter grpc.UnaryClientInterceptor, currentInvoker grpc.UnaryInvoker) grpc.UnaryInvoker {
-			return func(currentCtx context.Context, currentMethod string, currentReq, currentRepl interface{}, currentConn *grpc.ClientConn, currentOpts ...grpc.CallOption) error {
-				return currentInter(currentCtx, currentMethod, currentReq, currentRepl, currentConn, currentInvoker, currentOpts...)
-			}
-		}
-
-		chainedInvoker := invoker
-		for i := n - 1; i >= 0; i-- {
-			chainedInvoker = chainer(interceptors[i], chainedInvoker)
+	switch len(interceptors) {
+	case 0:
+		return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(ctx, method, req, reply, cc, opts...)
 		}
+	case 1:
+		return interceptors[0]
+	}
+	chain := append([]grpc.UnaryClientInterceptor(nil), interceptors...)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return chain[0](ctx, method, req, reply, cc, chainUnaryClientInvoker(chain, 0, invoker), opts...)
+	}
+}
 
-		return chainedInvoker(ctx, method, req, reply, cc, opts...)
+// chainUnaryClientInvoker returns the invoker passed to the interceptor at index curr, which calls
+// the next interceptor in the chain, or the final invoker if curr is the last one.
+func chainUnaryClientInvoker(chain []grpc.UnaryClientInterceptor, curr int, finalInvoker grpc.UnaryInvoker) grpc.UnaryInvoker {
+	if curr == len(chain)-1 {
+		return finalInvoker
+	}
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return chain[curr+1](ctx, method, req, reply, cc, chainUnaryClientInvoker(chain, curr+1, finalInvoker), opts...)
 	}
 }
 
@@ -86,22 +113,32 @@ func ChainUnaryClient(interceptors ...grpc.UnaryClientInterceptor) grpc.UnaryCli
 //
 // Execution is done in left-to-right order, including passing of context.
 // For example ChainStreamClient(one, two, three) will execute one before two before three.
+//
+// Like ChainUnaryServer, the chain is built once and streamers are only allocated as the call reaches them.
+// They stay valid after the interceptors return, e.g. for grpc_retry to re-establish streams.
 func ChainStreamClient(interceptors ...grpc.StreamClientInterceptor) grpc.StreamClientInterceptor {
-	n := len(interceptors)
-
-	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
-		chainer := func(currentInter grpc.StreamClientInterceptor, currentStreamer grpc.Streamer) grpc.Streamer {
-			return func(currentCtx context.Context, currentDesc *grpc.StreamDesc, currentConn *grpc.ClientConn, currentMethod string, currentOpts ...grpc.CallOption) (grpc.ClientStream, error) {
-				return currentInter(currentCtx, currentDesc, currentConn, currentMethod, currentStreamer, currentOpts...)
-			}
-		}
-
-		chainedStreamer := streamer
-		for i := n - 1; i >= 0; i-- {
-			chainedStreamer = chainer(interceptors[i], chainedStreamer)
+	switch len(interceptors) {
+	case 0:
+		return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(ctx, desc, cc, method, opts...)
 		}
+	case 1:
+		return interceptors[0]
+	}
+	chain := append([]grpc.StreamClientInterceptor(nil), interceptors...)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return chain[0](ctx, desc, cc, method, chainStreamClientStreamer(chain, 0, streamer), opts...)
+	}
+}
 
-		return chainedStreamer(ctx, desc, cc, method, opts...)
+// chainStreamClientStreamer returns the streamer passed to the interceptor at index curr, which calls
+// the next interceptor in the chain, or the final streamer if curr is the last one.
+func chainStreamClientStreamer(chain []grpc.StreamClientInterceptor, curr int, finalStreamer grpc.Streamer) grpc.Streamer {
+	if curr == len(chain)-1 {
+		return finalStreamer
+	}
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return chain[curr+1](ctx, desc, cc, method, chainStreamClientStreamer(chain, curr+1, finalStreamer), opts...)
 	}
 }
 
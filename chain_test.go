@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
@@ -170,3 +171,340 @@ func requireContextValue(t *testing.T, ctx context.Context, key string, msg ...i
 	require.NotNil(t, val, msg...)
 	require.Equal(t, someValue, val, msg...)
 }
+
+func TestChainAllocations(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 5, 10} {
+		// Every interceptor but the last allocates the handler it passes on.
+		maxAllocs := 0
+		if n > 1 {
+			maxAllocs = n - 1
+		}
+		unaryServer := ChainUnaryServer(nUnaryServerInterceptors(n)...)
+		streamServer := ChainStreamServer(nStreamServerInterceptors(n)...)
+		unaryClient := ChainUnaryClient(nUnaryClientInterceptors(n)...)
+		streamClient := ChainStreamClient(nStreamClientInterceptors(n)...)
+		allocs := testing.AllocsPerRun(100, func() {
+			unaryServer(parentContext, "input", parentUnaryInfo, noopUnaryHandler)
+		})
+		require.True(t, allocs <= float64(maxAllocs), "ChainUnaryServer of %d must allocate at most %d times per call, got %v", n, maxAllocs, allocs)
+		allocs = testing.AllocsPerRun(100, func() {
+			streamServer(nil, noopServerStream, parentStreamInfo, noopStreamHandler)
+		})
+		require.True(t, allocs <= float64(maxAllocs), "ChainStreamServer of %d must allocate at most %d times per call, got %v", n, maxAllocs, allocs)
+		allocs = testing.AllocsPerRun(100, func() {
+			unaryClient(parentContext, someServiceName, nil, nil, nil, noopInvoker)
+		})
+		require.True(t, allocs <= float64(maxAllocs), "ChainUnaryClient of %d must allocate at most %d times per call, got %v", n, maxAllocs, allocs)
+		allocs = testing.AllocsPerRun(100, func() {
+			streamClient(parentContext, noopStreamDesc, nil, someServiceName, noopStreamer)
+		})
+		require.True(t, allocs <= float64(maxAllocs), "ChainStreamClient of %d must allocate at most %d times per call, got %v", n, maxAllocs, allocs)
+	}
+}
+
+func TestChainAllocations_NotWorseThanPerCallChaining(t *testing.T) {
+	for _, n := range []int{1, 2, 5, 10} {
+		interceptors := nUnaryServerInterceptors(n)
+		chain := ChainUnaryServer(interceptors...)
+		perCall := perCallChainUnaryServer(interceptors...)
+		allocs := testing.AllocsPerRun(100, func() {
+			chain(parentContext, "input", parentUnaryInfo, noopUnaryHandler)
+		})
+		perCallAllocs := testing.AllocsPerRun(100, func() {
+			perCall(parentContext, "input", parentUnaryInfo, noopUnaryHandler)
+		})
+		require.True(t, allocs < perCallAllocs, "chain of %d must allocate less (%v) than per-call chaining (%v)", n, allocs, perCallAllocs)
+	}
+}
+
+func TestChainStreamClient_StreamerOutlivesCall(t *testing.T) {
+	var retained grpc.Streamer
+	retain := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		retained = streamer
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+	chain := ChainStreamClient(nStreamClientInterceptors(1)[0], retain, nStreamClientInterceptors(1)[0])
+	streamerFor := func(name string, calls *[]string) grpc.Streamer {
+		return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			*calls = append(*calls, name)
+			return nil, nil
+		}
+	}
+	var calls []string
+	_, err := chain(parentContext, noopStreamDesc, nil, someServiceName, streamerFor("first", &calls))
+	require.NoError(t, err)
+	firstRetained := retained
+	_, err = chain(parentContext, noopStreamDesc, nil, someServiceName, streamerFor("second", &calls))
+	require.NoError(t, err)
+	_, err = firstRetained(parentContext, noopStreamDesc, nil, someServiceName)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "second", "first"}, calls, "streamers must keep calling the final streamer of their call")
+}
+
+func TestChainUnaryServer_HandlerOutlivesCall(t *testing.T) {
+	var retained grpc.UnaryHandler
+	retain := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		retained = handler
+		return nil, nil
+	}
+	check := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(ctx, fmt.Sprintf("%v[%s]", req, info.FullMethod))
+	}
+	chain := ChainUnaryServer(check, retain, check)
+	handlerFor := func(name string) grpc.UnaryHandler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			return fmt.Sprintf("%v<%s>", req, name), nil
+		}
+	}
+	_, err := chain(parentContext, "first", &grpc.UnaryServerInfo{FullMethod: "one"}, handlerFor("one"))
+	require.NoError(t, err)
+	firstRetained := retained
+	_, err = chain(parentContext, "second", &grpc.UnaryServerInfo{FullMethod: "two"}, handlerFor("two"))
+	require.NoError(t, err)
+
+	done := make(chan interface{})
+	go func() {
+		resp, _ := firstRetained(parentContext, "late")
+		done <- resp
+	}()
+	assert.Equal(t, "late[one]<one>", <-done, "handlers must keep calling into their own call after the interceptor returns")
+}
+
+func TestChainUnaryServer_Reentrant(t *testing.T) {
+	var chain grpc.UnaryServerInterceptor
+	depth := 0
+	recurse := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if depth < 3 {
+			depth++
+			inner := &grpc.UnaryServerInfo{FullMethod: fmt.Sprintf("inner%d", depth)}
+			resp, err := chain(ctx, req, inner, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return fmt.Sprintf("%v<%s>", req, inner.FullMethod), nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			req = resp
+		}
+		return handler(ctx, req)
+	}
+	check := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(ctx, fmt.Sprintf("%v[%s]", req, info.FullMethod))
+	}
+	chain = ChainUnaryServer(recurse, check, check)
+	resp, err := chain(parentContext, "in", parentUnaryInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return req, nil
+	})
+	require.NoError(t, err)
+	expected := "in[inner3][inner3]<inner3>[inner2][inner2]<inner2>[inner1][inner1]<inner1>"
+	expected += fmt.Sprintf("[%s][%s]", someServiceName, someServiceName)
+	require.Equal(t, expected, resp, "nested calls of the chain must each see their own info and handler")
+}
+
+func BenchmarkChainUnaryServer(b *testing.B) {
+	for _, n := range benchmarkChainLengths {
+		interceptors := nUnaryServerInterceptors(n)
+		b.Run(fmt.Sprintf("interceptors=%d", n), func(b *testing.B) {
+			chain := ChainUnaryServer(interceptors...)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				chain(parentContext, "input", parentUnaryInfo, noopUnaryHandler)
+			}
+		})
+		b.Run(fmt.Sprintf("interceptors=%d/per_call_chaining", n), func(b *testing.B) {
+			chain := perCallChainUnaryServer(interceptors...)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				chain(parentContext, "input", parentUnaryInfo, noopUnaryHandler)
+			}
+		})
+	}
+}
+
+func BenchmarkChainStreamServer(b *testing.B) {
+	for _, n := range benchmarkChainLengths {
+		interceptors := nStreamServerInterceptors(n)
+		b.Run(fmt.Sprintf("interceptors=%d", n), func(b *testing.B) {
+			chain := ChainStreamServer(interceptors...)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				chain(nil, noopServerStream, parentStreamInfo, noopStreamHandler)
+			}
+		})
+		b.Run(fmt.Sprintf("interceptors=%d/per_call_chaining", n), func(b *testing.B) {
+			chain := perCallChainStreamServer(interceptors...)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				chain(nil, noopServerStream, parentStreamInfo, noopStreamHandler)
+			}
+		})
+	}
+}
+
+func BenchmarkChainUnaryClient(b *testing.B) {
+	for _, n := range benchmarkChainLengths {
+		interceptors := nUnaryClientInterceptors(n)
+		b.Run(fmt.Sprintf("interceptors=%d", n), func(b *testing.B) {
+			chain := ChainUnaryClient(interceptors...)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				chain(parentContext, someServiceName, nil, nil, nil, noopInvoker)
+			}
+		})
+		b.Run(fmt.Sprintf("interceptors=%d/per_call_chaining", n), func(b *testing.B) {
+			chain := perCallChainUnaryClient(interceptors...)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				chain(parentContext, someServiceName, nil, nil, nil, noopInvoker)
+			}
+		})
+	}
+}
+
+func BenchmarkChainStreamClient(b *testing.B) {
+	for _, n := range benchmarkChainLengths {
+		interceptors := nStreamClientInterceptors(n)
+		b.Run(fmt.Sprintf("interceptors=%d", n), func(b *testing.B) {
+			chain := ChainStreamClient(interceptors...)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				chain(parentContext, noopStreamDesc, nil, someServiceName, noopStreamer)
+			}
+		})
+		b.Run(fmt.Sprintf("interceptors=%d/per_call_chaining", n), func(b *testing.B) {
+			chain := perCallChainStreamClient(interceptors...)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				chain(parentContext, noopStreamDesc, nil, someServiceName, noopStreamer)
+			}
+		})
+	}
+}
+
+var (
+	benchmarkChainLengths = []int{1, 2, 5, 10}
+
+	noopServerStream = &fakeServerStream{ctx: parentContext}
+	noopStreamDesc   = &grpc.StreamDesc{ServerStreams: true, StreamName: someServiceName}
+)
+
+func noopUnaryHandler(ctx context.Context, req interface{}) (interface{}, error) {
+	return req, nil
+}
+
+func noopStreamHandler(srv interface{}, stream grpc.ServerStream) error {
+	return nil
+}
+
+func noopInvoker(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+	return nil
+}
+
+func noopStreamer(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, nil
+}
+
+func nUnaryServerInterceptors(n int) []grpc.UnaryServerInterceptor {
+	interceptors := make([]grpc.UnaryServerInterceptor, n)
+	for i := range interceptors {
+		interceptors[i] = func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			return handler(ctx, req)
+		}
+	}
+	return interceptors
+}
+
+func nStreamServerInterceptors(n int) []grpc.StreamServerInterceptor {
+	interceptors := make([]grpc.StreamServerInterceptor, n)
+	for i := range interceptors {
+		interceptors[i] = func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			return handler(srv, stream)
+		}
+	}
+	return interceptors
+}
+
+func nUnaryClientInterceptors(n int) []grpc.UnaryClientInterceptor {
+	interceptors := make([]grpc.UnaryClientInterceptor, n)
+	for i := range interceptors {
+		interceptors[i] = func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+	}
+	return interceptors
+}
+
+func nStreamClientInterceptors(n int) []grpc.StreamClientInterceptor {
+	interceptors := make([]grpc.StreamClientInterceptor, n)
+	for i := range interceptors {
+		interceptors[i] = func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+	}
+	return interceptors
+}
+
+// perCallChainUnaryServer and its siblings are the previous implementations of the chains, which wrap
+// every interceptor in a new handler on each call. They are kept as a baseline for allocation comparisons.
+func perCallChainUnaryServer(interceptors ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	n := len(interceptors)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		chainer := func(currentInter grpc.UnaryServerInterceptor, currentHandler grpc.UnaryHandler) grpc.UnaryHandler {
+			return func(currentCtx context.Context, currentReq interface{}) (interface{}, error) {
+				return currentInter(currentCtx, currentReq, info, currentHandler)
+			}
+		}
+		chainedHandler := handler
+		for i := n - 1; i >= 0; i-- {
+			chainedHandler = chainer(interceptors[i], chainedHandler)
+		}
+		return chainedHandler(ctx, req)
+	}
+}
+
+func perCallChainStreamServer(interceptors ...grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	n := len(interceptors)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		chainer := func(currentInter grpc.StreamServerInterceptor, currentHandler grpc.StreamHandler) grpc.StreamHandler {
+			return func(currentSrv interface{}, currentStream grpc.ServerStream) error {
+				return currentInter(currentSrv, currentStream, info, currentHandler)
+			}
+		}
+		chainedHandler := handler
+		for i := n - 1; i >= 0; i-- {
+			chainedHandler = chainer(interceptors[i], chainedHandler)
+		}
+		return chainedHandler(srv, ss)
+	}
+}
+
+func perCallChainUnaryClient(interceptors ...grpc.UnaryClientInterceptor) grpc.UnaryClientInterceptor {
+	n := len(interceptors)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		chainer := func(currentInter grpc.UnaryClientInterceptor, currentInvoker grpc.UnaryInvoker) grpc.UnaryInvoker {
+			return func(currentCtx context.Context, currentMethod string, currentReq, currentRepl interface{}, currentConn *grpc.ClientConn, currentOpts ...grpc.CallOption) error {
+				return currentInter(currentCtx, currentMethod, currentReq, currentRepl, currentConn, currentInvoker, currentOpts...)
+			}
+		}
+		chainedInvoker := invoker
+		for i := n - 1; i >= 0; i-- {
+			chainedInvoker = chainer(interceptors[i], chainedInvoker)
+		}
+		return chainedInvoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func perCallChainStreamClient(interceptors ...grpc.StreamClientInterceptor) grpc.StreamClientInterceptor {
+	n := len(interceptors)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		chainer := func(currentInter grpc.StreamClientInterceptor, currentStreamer grpc.Streamer) grpc.Streamer {
+			return func(currentCtx context.Context, currentDesc *grpc.StreamDesc, currentConn *grpc.ClientConn, currentMethod string, currentOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+				return currentInter(currentCtx, currentDesc, currentConn, currentMethod, currentStreamer, currentOpts...)
+			}
+		}
+		chainedStreamer := streamer
+		for i := n - 1; i >= 0; i-- {
+			chainedStreamer = chainer(interceptors[i], chainedStreamer)
+		}
+		return chainedStreamer(ctx, desc, cc, method, opts...)
+	}
+}
@@ -42,6 +42,65 @@ These interceptors will be executed from left to right: monitoring and then retr
 
 The retry interceptor will call every interceptor that follows it whenever when a retry happens.
 
+Middleware Bundles
+
+Most packages provide both unary and stream variants of their interceptors, and it is easy to forget
+one of them. Each package also provides a `Middleware` bundle of all of its variants, which can be
+installed together, in a consistent order:
+
+	myServer := grpc.NewServer(
+	    grpc_middleware.ServerOptions(
+	        grpc_recovery.Middleware(),
+	        grpc_ctxtags.Middleware(),
+	        grpc_zap.Middleware(zapLogger),
+	        grpc_auth.Middleware(myAuthFunction),
+	    )...,
+	)
+
+`DialOptions` does the same for the client-side variants.
+
+Some middleware only works in a particular position of a chain, e.g. payload logging needs the logging
+middleware to run before it. Packages declare these constraints by providing their middleware as a
+named `Stage`. `ChainStages` fails with a descriptive error when the constraints are violated, while
+`SortStages` reorders the stages deterministically to satisfy them:
+
+	stages, err := grpc_middleware.SortStages(
+	    grpc_zap.PayloadStage(zapLogger, serverDecider, clientDecider),
+	    grpc_zap.Stage(zapLogger),
+	    grpc_ctxtags.Stage(),
+	    grpc_recovery.Stage(),
+	)
+	if err != nil {
+	    return err
+	}
+	mw, err := grpc_middleware.ChainStages(stages...)
+
+Servers hosting several services that need different stacks can route each call to a chain of its
+service, with a default chain for all other services:
+
+	grpc.UnaryInterceptor(grpc_middleware.RouteUnaryServer(
+	    map[string]grpc.UnaryServerInterceptor{
+	        "example.PublicApi": grpc_middleware.ChainUnaryServer(publicAuthUnary, rateLimitUnary),
+	        "example.Admin":     adminAuthUnary,
+	    },
+	    grpc_middleware.ChainUnaryServer(publicAuthUnary),
+	))
+
+Chained interceptors are opaque functions. To verify the deployed middleware stack, build it as a
+`Chain` of named middleware instead, optionally restricted to some methods with a `grpc_selector`
+matcher, and expose its `DebugHandler` listing the middleware in effect for every method:
+
+	chain := grpc_middleware.NewChain(
+	    grpc_middleware.Named("tags", grpc_ctxtags.Middleware()),
+	    grpc_middleware.NamedMiddleware{
+	        Name:       "auth",
+	        Middleware: grpc_auth.Middleware(myAuthFunction),
+	        Matcher:    grpc_selector.Not(grpc_selector.MatchServices("grpc.health.v1.Health")),
+	    },
+	)
+	server := grpc.NewServer(grpc_middleware.ServerOptions(chain.Middleware())...)
+	http.Handle("/debug/middleware", chain.DebugHandler(server))
+
 Writing Your Own
 
 Implementing your own interceptor is pretty trivial: there are interfaces for that. But the interesting
@@ -65,5 +124,28 @@ needed. For example:
 	   newStream.WrappedContext = context.WithValue(ctx, "user_id", "john@example.com")
 	   return handler(srv, newStream)
 	}
+
+Middleware that only needs to observe calls, such as logging, tracing or metrics, does not need to
+be written four times. A `PreCallFunc` returning a `Reporter` for each call is enough to build all
+four interceptor kinds, see `ReportingMiddleware`:
+
+	func FakeMetricsPreCall(ctx context.Context, meta grpc_middleware.CallMeta) (grpc_middleware.Reporter, context.Context) {
+	   return &fakeMetricsReporter{service: meta.Service, method: meta.Method}, ctx
+	}
+
+On the client side, `WrappedClientStream` plays the same role, and additionally allows observing the
+calls made on the stream through optional hooks. For example:
+
+	func FakeLoggingClientStreamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	   clientStream, err := streamer(ctx, desc, cc, method, opts...)
+	   if err != nil {
+	      return nil, err
+	   }
+	   newStream := grpc_middleware.WrapClientStream(clientStream)
+	   newStream.OnRecvMsg = func(m interface{}, err error) {
+	      log.Printf("received %v, err: %v", m, err)
+	   }
+	   return newStream, nil
+	}
 */
 package grpc_middleware
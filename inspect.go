@@ -0,0 +1,198 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/rkollar/go-grpc-middleware/selector"
+	"google.golang.org/grpc"
+)
+
+// NamedMiddleware is Middleware with a name under which it shows up in chain listings.
+type NamedMiddleware struct {
+	Name       string
+	Middleware Middleware
+	// Matcher restricts the middleware to the calls it matches. If nil, the middleware runs for all calls.
+	Matcher grpc_selector.Matcher
+}
+
+// Named returns NamedMiddleware that runs for all calls.
+func Named(name string, mw Middleware) NamedMiddleware {
+	return NamedMiddleware{Name: name, Middleware: mw}
+}
+
+// NamedStages returns the stages as NamedMiddleware, in the same order, e.g. after sorting them with SortStages.
+func NamedStages(stages ...Stage) []NamedMiddleware {
+	named := make([]NamedMiddleware, 0, len(stages))
+	for _, s := range stages {
+		named = append(named, Named(s.Name, s.Middleware))
+	}
+	return named
+}
+
+// Chain is a chain of named middleware that can list the middleware in effect for each method.
+//
+// Unlike ChainMiddleware, which returns opaque interceptors, a Chain keeps track of what it was built from,
+// so that the deployed middleware stack can be verified at runtime, e.g. through its DebugHandler.
+type Chain struct {
+	links   []NamedMiddleware
+	chained Middleware
+}
+
+// NewChain creates a Chain out of the named middleware, executed in left-to-right order.
+func NewChain(links ...NamedMiddleware) *Chain {
+	c := &Chain{links: append([]NamedMiddleware(nil), links...)}
+	mw := make([]Middleware, 0, len(links))
+	for _, l := range c.links {
+		mw = append(mw, l.selected())
+	}
+	c.chained = ChainMiddleware(mw...)
+	return c
+}
+
+// Middleware returns the chained Middleware, with each link restricted to the calls matched by its Matcher.
+func (c *Chain) Middleware() Middleware {
+	return c.chained
+}
+
+// Effective returns the names of the middleware that runs for calls of the given method and type, in order.
+//
+// Matchers are evaluated with an empty context, so matchers that depend on the call's context may report
+// differently than they decide for real calls.
+func (c *Chain) Effective(fullMethod string, grpcType GRPCType, isClient bool) []string {
+	names := []string{}
+	for _, l := range c.links {
+		if !l.Middleware.has(grpcType, isClient) {
+			continue
+		}
+		if l.Matcher != nil && !l.Matcher.Match(context.Background(), fullMethod) {
+			continue
+		}
+		names = append(names, l.Name)
+	}
+	return names
+}
+
+// ServiceInfoProvider provides the services registered on a server. It is satisfied by *grpc.Server.
+type ServiceInfoProvider interface {
+	GetServiceInfo() map[string]grpc.ServiceInfo
+}
+
+// MethodChain lists the middleware in effect for a single method.
+type MethodChain struct {
+	FullMethod string   `json:"method"`
+	Type       GRPCType `json:"type"`
+	Middleware []string `json:"middleware"`
+}
+
+// ServerMethods lists the server middleware in effect for every method registered on the server, sorted by method name.
+func (c *Chain) ServerMethods(server ServiceInfoProvider) []MethodChain {
+	var methods []MethodChain
+	for service, info := range server.GetServiceInfo() {
+		for _, m := range info.Methods {
+			fullMethod := "/" + service + "/" + m.Name
+			grpcType := streamType(m.IsClientStream, m.IsServerStream)
+			methods = append(methods, MethodChain{
+				FullMethod: fullMethod,
+				Type:       grpcType,
+				Middleware: c.Effective(fullMethod, grpcType, false),
+			})
+		}
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].FullMethod < methods[j].FullMethod
+	})
+	return methods
+}
+
+type linkDescription struct {
+	Name    string `json:"name"`
+	Matcher string `json:"matcher,omitempty"`
+}
+
+type chainDescription struct {
+	Middleware []linkDescription `json:"middleware"`
+	Methods    []MethodChain     `json:"methods"`
+}
+
+// DebugHandler returns an http.Handler that lists the chain and the server middleware in effect for every method
+// registered on the server.
+//
+// The listing is plain text, unless JSON is requested with the `format=json` query parameter or an
+// `Accept: application/json` header.
+func (c *Chain) DebugHandler(server ServiceInfoProvider) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		desc := chainDescription{Middleware: []linkDescription{}, Methods: c.ServerMethods(server)}
+		for _, l := range c.links {
+			desc.Middleware = append(desc.Middleware, linkDescription{Name: l.Name, Matcher: describeMatcher(l.Matcher)})
+		}
+		if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(desc)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, "middleware:")
+		for _, l := range desc.Middleware {
+			if l.Matcher != "" {
+				fmt.Fprintf(w, "  %s [%s]\n", l.Name, l.Matcher)
+			} else {
+				fmt.Fprintf(w, "  %s\n", l.Name)
+			}
+		}
+		fmt.Fprintln(w, "methods:")
+		for _, m := range desc.Methods {
+			fmt.Fprintf(w, "  %s (%s): %s\n", m.FullMethod, m.Type, strings.Join(m.Middleware, ", "))
+		}
+	})
+}
+
+// describeMatcher describes the matcher with grpc_selector.Describe, and a link without one as empty.
+func describeMatcher(m grpc_selector.Matcher) string {
+	if m == nil {
+		return ""
+	}
+	return grpc_selector.Describe(m)
+}
+
+// has returns whether the middleware has an interceptor for calls of the given type.
+func (m Middleware) has(grpcType GRPCType, isClient bool) bool {
+	switch {
+	case grpcType == Unary && isClient:
+		return m.UnaryClient != nil
+	case grpcType == Unary:
+		return m.UnaryServer != nil
+	case isClient:
+		return m.StreamClient != nil
+	}
+	return m.StreamServer != nil
+}
+
+// selected returns the middleware restricted to the calls matched by the link's Matcher.
+func (l NamedMiddleware) selected() Middleware {
+	if l.Matcher == nil {
+		return l.Middleware
+	}
+	mw, matcher := l.Middleware, l.Matcher
+	selected := Middleware{}
+	if mw.UnaryServer != nil {
+		selected.UnaryServer = grpc_selector.UnaryServerInterceptor(mw.UnaryServer, matcher)
+	}
+	if mw.StreamServer != nil {
+		selected.StreamServer = grpc_selector.StreamServerInterceptor(mw.StreamServer, matcher)
+	}
+	if mw.UnaryClient != nil {
+		selected.UnaryClient = grpc_selector.UnaryClientInterceptor(mw.UnaryClient, matcher)
+	}
+	if mw.StreamClient != nil {
+		selected.StreamClient = grpc_selector.StreamClientInterceptor(mw.StreamClient, matcher)
+	}
+	return selected
+}
@@ -0,0 +1,147 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+const (
+	pingMethod   = "/mwitkow.testproto.TestService/Ping"
+	listMethod   = "/mwitkow.testproto.TestService/PingList"
+	healthMethod = "/grpc.health.v1.Health/Check"
+)
+
+type fakeServiceInfoProvider map[string]grpc.ServiceInfo
+
+func (f fakeServiceInfoProvider) GetServiceInfo() map[string]grpc.ServiceInfo {
+	return f
+}
+
+var testServices = fakeServiceInfoProvider{
+	"mwitkow.testproto.TestService": {Methods: []grpc.MethodInfo{
+		{Name: "PingList", IsServerStream: true},
+		{Name: "Ping"},
+	}},
+	"grpc.health.v1.Health": {Methods: []grpc.MethodInfo{{Name: "Check"}}},
+}
+
+// skipMethod matches all methods but one.
+type skipMethod string
+
+func (s skipMethod) Match(_ context.Context, fullMethodName string) bool {
+	return fullMethodName != string(s)
+}
+
+func (s skipMethod) String() string {
+	return "not(" + string(s) + ")"
+}
+
+func recordingMiddleware(name string, calls *[]string) Middleware {
+	return Middleware{
+		UnaryServer: func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			*calls = append(*calls, name)
+			return handler(ctx, req)
+		},
+		StreamServer: func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			*calls = append(*calls, name)
+			return handler(srv, stream)
+		},
+	}
+}
+
+func TestChain_Effective(t *testing.T) {
+	var calls []string
+	unaryOnly := recordingMiddleware("validator", &calls)
+	unaryOnly.StreamServer = nil
+	chain := NewChain(
+		Named("tags", recordingMiddleware("tags", &calls)),
+		NamedMiddleware{Name: "auth", Middleware: recordingMiddleware("auth", &calls), Matcher: skipMethod(healthMethod)},
+		Named("validator", unaryOnly),
+	)
+
+	assert.Equal(t, []string{"tags", "auth", "validator"}, chain.Effective(pingMethod, Unary, false), "all middleware must run for matched unary methods")
+	assert.Equal(t, []string{"tags", "validator"}, chain.Effective(healthMethod, Unary, false), "unmatched middleware must not be listed")
+	assert.Equal(t, []string{"tags", "auth"}, chain.Effective(listMethod, ServerStream, false), "middleware without a stream interceptor must not be listed")
+	assert.Equal(t, []string{}, chain.Effective(pingMethod, Unary, true), "server middleware must not be listed for clients")
+
+	_, err := chain.Middleware().UnaryServer(parentContext, "input", &grpc.UnaryServerInfo{FullMethod: healthMethod}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, chain.Effective(healthMethod, Unary, false), calls, "listed middleware must match the middleware that runs")
+
+	calls = nil
+	err = chain.Middleware().StreamServer(nil, &fakeServerStream{ctx: parentContext}, &grpc.StreamServerInfo{FullMethod: listMethod, IsServerStream: true}, func(srv interface{}, stream grpc.ServerStream) error {
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, chain.Effective(listMethod, ServerStream, false), calls, "listed middleware must match the middleware that runs")
+}
+
+func TestChain_ServerMethods(t *testing.T) {
+	chain := NewChain(
+		Named("tags", recordingMiddleware("tags", new([]string))),
+		NamedMiddleware{Name: "auth", Middleware: recordingMiddleware("auth", new([]string)), Matcher: skipMethod(healthMethod)},
+	)
+	assert.Equal(t, []MethodChain{
+		{FullMethod: healthMethod, Type: Unary, Middleware: []string{"tags"}},
+		{FullMethod: pingMethod, Type: Unary, Middleware: []string{"tags", "auth"}},
+		{FullMethod: listMethod, Type: ServerStream, Middleware: []string{"tags", "auth"}},
+	}, chain.ServerMethods(testServices), "methods must be listed in order with their middleware")
+}
+
+func TestChain_DebugHandler(t *testing.T) {
+	chain := NewChain(
+		Named("tags", recordingMiddleware("tags", new([]string))),
+		NamedMiddleware{Name: "auth", Middleware: recordingMiddleware("auth", new([]string)), Matcher: skipMethod(healthMethod)},
+	)
+	handler := chain.DebugHandler(testServices)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/middleware", nil))
+	assert.Equal(t, strings.Join([]string{
+		"middleware:",
+		"  tags",
+		"  auth [not(/grpc.health.v1.Health/Check)]",
+		"methods:",
+		"  /grpc.health.v1.Health/Check (unary): tags",
+		"  /mwitkow.testproto.TestService/Ping (unary): tags, auth",
+		"  /mwitkow.testproto.TestService/PingList (server_stream): tags, auth",
+		"",
+	}, "\n"), rec.Body.String(), "text listing must describe the chain")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/middleware?format=json", nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var listing struct {
+		Middleware []struct {
+			Name    string `json:"name"`
+			Matcher string `json:"matcher"`
+		} `json:"middleware"`
+		Methods []MethodChain `json:"methods"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listing), "JSON listing must be valid")
+	require.Len(t, listing.Middleware, 2)
+	assert.Equal(t, "not(/grpc.health.v1.Health/Check)", listing.Middleware[1].Matcher, "JSON listing must describe matchers")
+	assert.Equal(t, chain.ServerMethods(testServices), listing.Methods, "JSON listing must list the methods")
+}
+
+func TestNamedStages(t *testing.T) {
+	stages, err := SortStages(
+		Stage{Name: "payload", Middleware: recordingMiddleware("payload", new([]string)), After: []string{"logging"}},
+		Stage{Name: "logging", Middleware: recordingMiddleware("logging", new([]string))},
+	)
+	require.NoError(t, err)
+	chain := NewChain(NamedStages(stages...)...)
+	assert.Equal(t, []string{"logging", "payload"}, chain.Effective(pingMethod, Unary, false), "stages must be listed by name in order")
+}
@@ -10,8 +10,9 @@ import (
 	"github.com/go-kit/kit/log/level"
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
-	grpc_logging "github.com/grpc-ecosystem/go-grpc-middleware/logging"
-	"github.com/grpc-ecosystem/go-grpc-middleware/logging/kit/ctxkit"
+	grpc_middleware "github.com/rkollar/go-grpc-middleware"
+	grpc_logging "github.com/rkollar/go-grpc-middleware/logging"
+	"github.com/rkollar/go-grpc-middleware/logging/kit/ctxkit"
 	"google.golang.org/grpc"
 )
 
@@ -80,30 +81,28 @@ func PayloadStreamClientInterceptor(logger log.Logger, decider grpc_logging.Clie
 		}
 		logEntry := log.With(logger, newClientLoggerFields(ctx, method)...)
 		clientStream, err := streamer(ctx, desc, cc, method, opts...)
-		newStream := &loggingClientStream{ClientStream: clientStream, logger: logEntry}
-		return newStream, err
+		if err != nil {
+			return nil, err
+		}
+		return newLoggingClientStream(clientStream, logEntry), nil
 	}
 }
 
-type loggingClientStream struct {
-	grpc.ClientStream
-	logger log.Logger
-}
-
-func (l *loggingClientStream) SendMsg(m interface{}) error {
-	err := l.ClientStream.SendMsg(m)
-	if err == nil {
-		logProtoMessageAsJson(l.logger, m, "grpc.request.content", "server request payload logged as grpc.request.content field")
+// newLoggingClientStream wraps the grpc.ClientStream so that all successfully sent and received
+// messages are logged.
+func newLoggingClientStream(clientStream grpc.ClientStream, logger log.Logger) grpc.ClientStream {
+	wrapped := grpc_middleware.WrapClientStream(clientStream)
+	wrapped.OnSendMsg = func(m interface{}, err error) {
+		if err == nil {
+			logProtoMessageAsJson(logger, m, "grpc.request.content", "server request payload logged as grpc.request.content field")
+		}
 	}
-	return err
-}
-
-func (l *loggingClientStream) RecvMsg(m interface{}) error {
-	err := l.ClientStream.RecvMsg(m)
-	if err == nil {
-		logProtoMessageAsJson(l.logger, m, "grpc.response.content", "server response payload logged as grpc.response.content field")
+	wrapped.OnRecvMsg = func(m interface{}, err error) {
+		if err == nil {
+			logProtoMessageAsJson(logger, m, "grpc.response.content", "server response payload logged as grpc.response.content field")
+		}
 	}
-	return err
+	return wrapped
 }
 
 type loggingServerStream struct {
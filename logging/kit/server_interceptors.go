@@ -7,8 +7,10 @@ import (
 	"context"
 
 	"github.com/go-kit/kit/log"
-	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
-	"github.com/grpc-ecosystem/go-grpc-middleware/logging/kit/ctxkit"
+	grpc_middleware "github.com/rkollar/go-grpc-middleware"
+	grpc_logging "github.com/rkollar/go-grpc-middleware/logging"
+	"github.com/rkollar/go-grpc-middleware/logging/kit/ctxkit"
+	"github.com/rkollar/go-grpc-middleware/tags"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 )
@@ -61,6 +63,49 @@ func StreamServerInterceptor(logger log.Logger, opts ...Option) grpc.StreamServe
 	}
 }
 
+// Middleware returns a new grpc_middleware.Middleware that logs the execution of calls with the given
+// log.Logger, both on the server and the client side.
+func Middleware(logger log.Logger, opts ...Option) grpc_middleware.Middleware {
+	return grpc_middleware.Middleware{
+		UnaryServer:  UnaryServerInterceptor(logger, opts...),
+		StreamServer: StreamServerInterceptor(logger, opts...),
+		UnaryClient:  UnaryClientInterceptor(logger, opts...),
+		StreamClient: StreamClientInterceptor(logger, opts...),
+	}
+}
+
+const (
+	// StageName is the name of the grpc_kit logging stage in chains of grpc_middleware.Stage.
+	StageName = "grpc_kit"
+	// PayloadStageName is the name of the grpc_kit payload logging stage in chains of grpc_middleware.Stage.
+	PayloadStageName = "grpc_kit.payload"
+)
+
+// Stage returns the logging Middleware of this package as a grpc_middleware.Stage, which runs after
+// the grpc_ctxtags stage so that request tags are logged.
+func Stage(logger log.Logger, opts ...Option) grpc_middleware.Stage {
+	return grpc_middleware.Stage{
+		Name:       StageName,
+		Middleware: Middleware(logger, opts...),
+		After:      []string{grpc_ctxtags.StageName},
+	}
+}
+
+// PayloadStage returns the payload logging interceptors of this package as a grpc_middleware.Stage,
+// which requires the logging stage to run before it.
+func PayloadStage(logger log.Logger, serverDecider grpc_logging.ServerPayloadLoggingDecider, clientDecider grpc_logging.ClientPayloadLoggingDecider) grpc_middleware.Stage {
+	return grpc_middleware.Stage{
+		Name: PayloadStageName,
+		Middleware: grpc_middleware.Middleware{
+			UnaryServer:  PayloadUnaryServerInterceptor(logger, serverDecider),
+			StreamServer: PayloadStreamServerInterceptor(logger, serverDecider),
+			UnaryClient:  PayloadUnaryClientInterceptor(logger, clientDecider),
+			StreamClient: PayloadStreamClientInterceptor(logger, clientDecider),
+		},
+		Requires: []string{StageName},
+	}
+}
+
 func injectLogger(ctx context.Context, logger log.Logger, fullMethodString string, start time.Time) context.Context {
 	f := ctxkit.TagsToFields(ctx)
 	f = append(f, "grpc.start_time", start.Format(time.RFC3339))
@@ -7,8 +7,9 @@ import (
 
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
-	"github.com/grpc-ecosystem/go-grpc-middleware/logging"
-	"github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus/ctxlogrus"
+	grpc_middleware "github.com/rkollar/go-grpc-middleware"
+	"github.com/rkollar/go-grpc-middleware/logging"
+	"github.com/rkollar/go-grpc-middleware/logging/logrus/ctxlogrus"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 )
@@ -79,30 +80,28 @@ func PayloadStreamClientInterceptor(entry *logrus.Entry, decider grpc_logging.Cl
 		}
 		logEntry := entry.WithFields(newClientLoggerFields(ctx, method))
 		clientStream, err := streamer(ctx, desc, cc, method, opts...)
-		newStream := &loggingClientStream{ClientStream: clientStream, entry: logEntry}
-		return newStream, err
+		if err != nil {
+			return nil, err
+		}
+		return newLoggingClientStream(clientStream, logEntry), nil
 	}
 }
 
-type loggingClientStream struct {
-	grpc.ClientStream
-	entry *logrus.Entry
-}
-
-func (l *loggingClientStream) SendMsg(m interface{}) error {
-	err := l.ClientStream.SendMsg(m)
-	if err == nil {
-		logProtoMessageAsJson(l.entry, m, "grpc.request.content", "server request payload logged as grpc.request.content field")
+// newLoggingClientStream wraps the grpc.ClientStream so that all successfully sent and received
+// messages are logged.
+func newLoggingClientStream(clientStream grpc.ClientStream, entry *logrus.Entry) grpc.ClientStream {
+	wrapped := grpc_middleware.WrapClientStream(clientStream)
+	wrapped.OnSendMsg = func(m interface{}, err error) {
+		if err == nil {
+			logProtoMessageAsJson(entry, m, "grpc.request.content", "server request payload logged as grpc.request.content field")
+		}
 	}
-	return err
-}
-
-func (l *loggingClientStream) RecvMsg(m interface{}) error {
-	err := l.ClientStream.RecvMsg(m)
-	if err == nil {
-		logProtoMessageAsJson(l.entry, m, "grpc.response.content", "server response payload logged as grpc.response.content field")
+	wrapped.OnRecvMsg = func(m interface{}, err error) {
+		if err == nil {
+			logProtoMessageAsJson(entry, m, "grpc.response.content", "server response payload logged as grpc.response.content field")
+		}
 	}
-	return err
+	return wrapped
 }
 
 type loggingServerStream struct {
@@ -7,8 +7,10 @@ import (
 	"path"
 	"time"
 
-	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
-	"github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus/ctxlogrus"
+	grpc_middleware "github.com/rkollar/go-grpc-middleware"
+	grpc_logging "github.com/rkollar/go-grpc-middleware/logging"
+	"github.com/rkollar/go-grpc-middleware/logging/logrus/ctxlogrus"
+	"github.com/rkollar/go-grpc-middleware/tags"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 )
@@ -76,6 +78,49 @@ func StreamServerInterceptor(entry *logrus.Entry, opts ...Option) grpc.StreamSer
 	}
 }
 
+// Middleware returns a new grpc_middleware.Middleware that logs the execution of calls with the given
+// logrus.Entry, both on the server and the client side.
+func Middleware(entry *logrus.Entry, opts ...Option) grpc_middleware.Middleware {
+	return grpc_middleware.Middleware{
+		UnaryServer:  UnaryServerInterceptor(entry, opts...),
+		StreamServer: StreamServerInterceptor(entry, opts...),
+		UnaryClient:  UnaryClientInterceptor(entry, opts...),
+		StreamClient: StreamClientInterceptor(entry, opts...),
+	}
+}
+
+const (
+	// StageName is the name of the grpc_logrus logging stage in chains of grpc_middleware.Stage.
+	StageName = "grpc_logrus"
+	// PayloadStageName is the name of the grpc_logrus payload logging stage in chains of grpc_middleware.Stage.
+	PayloadStageName = "grpc_logrus.payload"
+)
+
+// Stage returns the logging Middleware of this package as a grpc_middleware.Stage, which runs after
+// the grpc_ctxtags stage so that request tags are logged.
+func Stage(entry *logrus.Entry, opts ...Option) grpc_middleware.Stage {
+	return grpc_middleware.Stage{
+		Name:       StageName,
+		Middleware: Middleware(entry, opts...),
+		After:      []string{grpc_ctxtags.StageName},
+	}
+}
+
+// PayloadStage returns the payload logging interceptors of this package as a grpc_middleware.Stage,
+// which requires the logging stage to run before it.
+func PayloadStage(entry *logrus.Entry, serverDecider grpc_logging.ServerPayloadLoggingDecider, clientDecider grpc_logging.ClientPayloadLoggingDecider) grpc_middleware.Stage {
+	return grpc_middleware.Stage{
+		Name: PayloadStageName,
+		Middleware: grpc_middleware.Middleware{
+			UnaryServer:  PayloadUnaryServerInterceptor(entry, serverDecider),
+			StreamServer: PayloadStreamServerInterceptor(entry, serverDecider),
+			UnaryClient:  PayloadUnaryClientInterceptor(entry, clientDecider),
+			StreamClient: PayloadStreamClientInterceptor(entry, clientDecider),
+		},
+		Requires: []string{StageName},
+	}
+}
+
 func newLoggerForCall(ctx context.Context, entry *logrus.Entry, fullMethodString string, start time.Time) context.Context {
 	service := path.Dir(fullMethodString)[1:]
 	method := path.Base(fullMethodString)
@@ -5,8 +5,10 @@ import (
 	"path"
 	"time"
 
-	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
-	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	grpc_middleware "github.com/rkollar/go-grpc-middleware"
+	grpc_logging "github.com/rkollar/go-grpc-middleware/logging"
+	"github.com/rkollar/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/rkollar/go-grpc-middleware/tags"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"google.golang.org/grpc"
@@ -63,6 +65,49 @@ func StreamServerInterceptor(logger *zap.Logger, opts ...Option) grpc.StreamServ
 	}
 }
 
+// Middleware returns a new grpc_middleware.Middleware that logs the execution of calls with the given
+// zap.Logger, both on the server and the client side.
+func Middleware(logger *zap.Logger, opts ...Option) grpc_middleware.Middleware {
+	return grpc_middleware.Middleware{
+		UnaryServer:  UnaryServerInterceptor(logger, opts...),
+		StreamServer: StreamServerInterceptor(logger, opts...),
+		UnaryClient:  UnaryClientInterceptor(logger, opts...),
+		StreamClient: StreamClientInterceptor(logger, opts...),
+	}
+}
+
+const (
+	// StageName is the name of the grpc_zap logging stage in chains of grpc_middleware.Stage.
+	StageName = "grpc_zap"
+	// PayloadStageName is the name of the grpc_zap payload logging stage in chains of grpc_middleware.Stage.
+	PayloadStageName = "grpc_zap.payload"
+)
+
+// Stage returns the logging Middleware of this package as a grpc_middleware.Stage, which runs after
+// the grpc_ctxtags stage so that request tags are logged.
+func Stage(logger *zap.Logger, opts ...Option) grpc_middleware.Stage {
+	return grpc_middleware.Stage{
+		Name:       StageName,
+		Middleware: Middleware(logger, opts...),
+		After:      []string{grpc_ctxtags.StageName},
+	}
+}
+
+// PayloadStage returns the payload logging interceptors of this package as a grpc_middleware.Stage,
+// which requires the logging stage to run before it.
+func PayloadStage(logger *zap.Logger, serverDecider grpc_logging.ServerPayloadLoggingDecider, clientDecider grpc_logging.ClientPayloadLoggingDecider) grpc_middleware.Stage {
+	return grpc_middleware.Stage{
+		Name: PayloadStageName,
+		Middleware: grpc_middleware.Middleware{
+			UnaryServer:  PayloadUnaryServerInterceptor(logger, serverDecider),
+			StreamServer: PayloadStreamServerInterceptor(logger, serverDecider),
+			UnaryClient:  PayloadUnaryClientInterceptor(logger, clientDecider),
+			StreamClient: PayloadStreamClientInterceptor(logger, clientDecider),
+		},
+		Requires: []string{StageName},
+	}
+}
+
 func serverCallFields(fullMethodString string) []zapcore.Field {
 	service := path.Dir(fullMethodString)[1:]
 	method := path.Base(fullMethodString)
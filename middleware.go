@@ -0,0 +1,92 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_middleware
+
+import (
+	"google.golang.org/grpc"
+)
+
+// Middleware bundles the unary and stream variants of a piece of middleware, so that they can be
+// installed together.
+//
+// Any of the interceptors may be nil, e.g. server-only middleware leaves the client interceptors unset.
+type Middleware struct {
+	UnaryServer  grpc.UnaryServerInterceptor
+	StreamServer grpc.StreamServerInterceptor
+	UnaryClient  grpc.UnaryClientInterceptor
+	StreamClient grpc.StreamClientInterceptor
+}
+
+// ChainMiddleware creates a single Middleware out of many, chaining each of the interceptor kinds
+// separately.
+//
+// Execution is done in left-to-right order, the same as in ChainUnaryServer and its siblings. Interceptor
+// kinds that are not set in any of the middleware are left nil in the result.
+func ChainMiddleware(mw ...Middleware) Middleware {
+	var (
+		unaryServer  []grpc.UnaryServerInterceptor
+		streamServer []grpc.StreamServerInterceptor
+		unaryClient  []grpc.UnaryClientInterceptor
+		streamClient []grpc.StreamClientInterceptor
+	)
+	for _, m := range mw {
+		if m.UnaryServer != nil {
+			unaryServer = append(unaryServer, m.UnaryServer)
+		}
+		if m.StreamServer != nil {
+			streamServer = append(streamServer, m.StreamServer)
+		}
+		if m.UnaryClient != nil {
+			unaryClient = append(unaryClient, m.UnaryClient)
+		}
+		if m.StreamClient != nil {
+			streamClient = append(streamClient, m.StreamClient)
+		}
+	}
+	chained := Middleware{}
+	if len(unaryServer) > 0 {
+		chained.UnaryServer = ChainUnaryServer(unaryServer...)
+	}
+	if len(streamServer) > 0 {
+		chained.StreamServer = ChainStreamServer(streamServer...)
+	}
+	if len(unaryClient) > 0 {
+		chained.UnaryClient = ChainUnaryClient(unaryClient...)
+	}
+	if len(streamClient) > 0 {
+		chained.StreamClient = ChainStreamClient(streamClient...)
+	}
+	return chained
+}
+
+// ServerOptions returns the grpc.Server config options that install the server interceptors of all
+// of the middleware, in left-to-right order.
+//
+// As gRPC accepts only a single unary and a single stream interceptor, these options must not be
+// combined with grpc.UnaryInterceptor, grpc.StreamInterceptor or WithUnaryServerChain and its siblings.
+func ServerOptions(mw ...Middleware) []grpc.ServerOption {
+	chained := ChainMiddleware(mw...)
+	var opts []grpc.ServerOption
+	if chained.UnaryServer != nil {
+		opts = append(opts, grpc.UnaryInterceptor(chained.UnaryServer))
+	}
+	if chained.StreamServer != nil {
+		opts = append(opts, grpc.StreamInterceptor(chained.StreamServer))
+	}
+	return opts
+}
+
+// DialOptions returns the grpc.Dial options that install the client interceptors of all of the
+// middleware, in left-to-right order.
+func DialOptions(mw ...Middleware) []grpc.DialOption {
+	chained := ChainMiddleware(mw...)
+	var opts []grpc.DialOption
+	if chained.UnaryClient != nil {
+		opts = append(opts, grpc.WithUnaryInterceptor(chained.UnaryClient))
+	}
+	if chained.StreamClient != nil {
+		opts = append(opts, grpc.WithStreamInterceptor(chained.StreamClient))
+	}
+	return opts
+}
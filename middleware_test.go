@@ -0,0 +1,80 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestChainMiddleware(t *testing.T) {
+	var calls []string
+	record := func(name string) Middleware {
+		return Middleware{
+			UnaryServer: func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+				calls = append(calls, name+".unary_server")
+				return handler(ctx, req)
+			},
+			StreamServer: func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				calls = append(calls, name+".stream_server")
+				return handler(srv, stream)
+			},
+			UnaryClient: func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+				calls = append(calls, name+".unary_client")
+				return invoker(ctx, method, req, reply, cc, opts...)
+			},
+			StreamClient: func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+				calls = append(calls, name+".stream_client")
+				return streamer(ctx, desc, cc, method, opts...)
+			},
+		}
+	}
+	serverOnly := Middleware{
+		UnaryServer: func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			calls = append(calls, "server_only.unary_server")
+			return handler(ctx, req)
+		},
+	}
+	chained := ChainMiddleware(record("first"), serverOnly, record("second"))
+
+	_, err := chained.UnaryServer(parentContext, "input", parentUnaryInfo, noopUnaryHandler)
+	require.NoError(t, err)
+	require.NoError(t, chained.StreamServer(nil, noopServerStream, parentStreamInfo, noopStreamHandler))
+	require.NoError(t, chained.UnaryClient(parentContext, someServiceName, nil, nil, nil, noopInvoker))
+	_, err = chained.StreamClient(parentContext, noopStreamDesc, nil, someServiceName, noopStreamer)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"first.unary_server", "server_only.unary_server", "second.unary_server",
+		"first.stream_server", "second.stream_server",
+		"first.unary_client", "second.unary_client",
+		"first.stream_client", "second.stream_client",
+	}, calls, "all interceptor kinds must be chained in left-to-right order")
+}
+
+func TestChainMiddleware_LeavesMissingKindsNil(t *testing.T) {
+	chained := ChainMiddleware(Middleware{UnaryServer: nUnaryServerInterceptors(1)[0]})
+	assert.NotNil(t, chained.UnaryServer, "set interceptor kinds must be chained")
+	assert.Nil(t, chained.StreamServer, "unset interceptor kinds must be left nil")
+	assert.Nil(t, chained.UnaryClient, "unset interceptor kinds must be left nil")
+	assert.Nil(t, chained.StreamClient, "unset interceptor kinds must be left nil")
+}
+
+func TestServerAndDialOptions(t *testing.T) {
+	serverOnly := Middleware{
+		UnaryServer:  nUnaryServerInterceptors(1)[0],
+		StreamServer: nStreamServerInterceptors(1)[0],
+	}
+	clientOnly := Middleware{
+		UnaryClient: nUnaryClientInterceptors(1)[0],
+	}
+	assert.Len(t, ServerOptions(serverOnly, clientOnly), 2, "both server interceptor kinds must be installed")
+	assert.Len(t, DialOptions(serverOnly, clientOnly), 1, "only the set client interceptor kinds must be installed")
+	assert.Empty(t, DialOptions(serverOnly), "no dial options must be returned for server-only middleware")
+	assert.NotPanics(t, func() { grpc.NewServer(ServerOptions(serverOnly, serverOnly)...) }, "options must be accepted by grpc.Server")
+}
@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	grpc_middleware "github.com/rkollar/go-grpc-middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Mode is what the client and message interceptors do with calls and messages exceeding the limit.
+type Mode int
+
+const (
+	// Wait blocks calls and messages until the limiter lets them through, failing them early with
+	// `ResourceExhausted` if that would take longer than their deadline.
+	Wait Mode = iota
+	// FailFast fails calls and messages with `ResourceExhausted` right away.
+	FailFast
+)
+
+const (
+	// pollInterval is how often waiting calls retry limiters that are not DelayLimiters.
+	pollInterval = 10 * time.Millisecond
+	// minWait is the shortest time waiting calls wait, so that they do not spin on rounding errors of delays.
+	minWait = time.Millisecond
+)
+
+type clientOptions struct {
+	mode        Mode
+	methodModes map[string]Mode
+}
+
+// ClientOption configures the client-side rate limiting interceptors.
+type ClientOption func(*clientOptions)
+
+func evaluateClientOptions(opts []ClientOption) *clientOptions {
+	o := &clientOptions{mode: Wait, methodModes: map[string]Mode{}}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithMode sets what is done with calls exceeding the limit. Defaults to Wait.
+func WithMode(mode Mode) ClientOption {
+	return func(o *clientOptions) {
+		o.mode = mode
+	}
+}
+
+// WithMethodMode sets what is done with calls of the method, e.g. `/mwitkow.testproto.TestService/Ping`,
+// exceeding the limit, overriding WithMode.
+func WithMethodMode(fullMethod string, mode Mode) ClientOption {
+	return func(o *clientOptions) {
+		o.methodModes[fullMethod] = mode
+	}
+}
+
+// UnaryClientInterceptor returns a new unary client interceptor that throttles outgoing calls by the limiter.
+func UnaryClientInterceptor(limiter Limiter, opts ...ClientOption) grpc.UnaryClientInterceptor {
+	o := evaluateClientOptions(opts)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if err := throttle(ctx, limiter, o.modeFor(method), method); err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor returns a new streaming client interceptor that throttles opening streams by the limiter.
+func StreamClientInterceptor(limiter Limiter, opts ...ClientOption) grpc.StreamClientInterceptor {
+	o := evaluateClientOptions(opts)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if err := throttle(ctx, limiter, o.modeFor(method), method); err != nil {
+			return nil, err
+		}
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+// ClientMiddleware returns a new grpc_middleware.Middleware that throttles outgoing calls on the client side.
+func ClientMiddleware(limiter Limiter, opts ...ClientOption) grpc_middleware.Middleware {
+	return grpc_middleware.Middleware{
+		UnaryClient:  UnaryClientInterceptor(limiter, opts...),
+		StreamClient: StreamClientInterceptor(limiter, opts...),
+	}
+}
+
+func (o *clientOptions) modeFor(method string) Mode {
+	if mode, ok := o.methodModes[method]; ok {
+		return mode
+	}
+	return o.mode
+}
+
+// throttle returns once the limiter lets the call through, or an error if the call is rejected. The name of what
+// is throttled, e.g. the method of the call, is used in the errors.
+func throttle(ctx context.Context, limiter Limiter, mode Mode, name string) error {
+	for {
+		if !limiter.Limit() {
+			return nil
+		}
+		if mode == FailFast {
+			return status.Errorf(codes.ResourceExhausted, "%s is rejected by grpc_ratelimit middleware, please retry later.", name)
+		}
+		wait := pollInterval
+		if d, ok := limiter.(DelayLimiter); ok {
+			wait = d.Delay()
+		}
+		if wait < minWait {
+			wait = minWait
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return status.Errorf(codes.ResourceExhausted, "%s is rejected by grpc_ratelimit middleware, the limit would be exceeded until its deadline.", name)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			if ctx.Err() == context.DeadlineExceeded {
+				return status.Error(codes.DeadlineExceeded, ctx.Err().Error())
+			}
+			return status.Error(codes.Canceled, ctx.Err().Error())
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	_ DelayLimiter = (*TokenBucket)(nil)
+	_ DelayLimiter = (*LeakyBucket)(nil)
+	_ DelayLimiter = (*FixedWindow)(nil)
+	_ DelayLimiter = (*SlidingWindow)(nil)
+)
+
+func TestLimiters_Delay(t *testing.T) {
+	clock := newFakeClock()
+	for name, limiter := range map[string]DelayLimiter{
+		"TokenBucket":   NewTokenBucket(10, 2, WithClock(clock.Now)),
+		"LeakyBucket":   NewLeakyBucket(10, 2, WithClock(clock.Now)),
+		"FixedWindow":   NewFixedWindow(2, 200*time.Millisecond, WithClock(clock.Now)),
+		"SlidingWindow": NewSlidingWindow(2, 200*time.Millisecond, WithClock(clock.Now)),
+	} {
+		assert.Equal(t, time.Duration(0), limiter.Delay(), "%s must not delay requests it lets through", name)
+		allowed(limiter, 2)
+		delay := limiter.Delay()
+		assert.True(t, delay > 0, "%s must delay requests it limits", name)
+		clock.Advance(delay - time.Millisecond)
+		assert.True(t, limiter.Limit(), "%s must limit requests before the delay", name)
+		clock.Advance(time.Millisecond)
+		assert.Equal(t, time.Duration(0), limiter.Delay(), "%s must not delay requests after the delay", name)
+		assert.False(t, limiter.Limit(), "%s must let requests through after the delay", name)
+	}
+}
+
+func TestSlidingWindow_DelayIntoNextWindow(t *testing.T) {
+	clock := newFakeClock()
+	window := NewSlidingWindow(4, time.Second, WithClock(clock.Now))
+	clock.Advance(500 * time.Millisecond)
+	allowed(window, 4)
+	assert.Equal(t, 500*time.Millisecond+time.Nanosecond, window.Delay(), "requests of the current window must slide out in the next one")
+}
+
+func invokeUnary(interceptor grpc.UnaryClientInterceptor, ctx context.Context, method string) error {
+	return interceptor(ctx, method, "request", nil, nil, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return nil
+	})
+}
+
+func TestUnaryClientInterceptor_Wait(t *testing.T) {
+	interceptor := UnaryClientInterceptor(NewTokenBucket(20, 1))
+	start := time.Now()
+	assert.NoError(t, invokeUnary(interceptor, context.TODO(), pingMethod))
+	assert.NoError(t, invokeUnary(interceptor, context.TODO(), pingMethod), "calls must wait for the limiter")
+	assert.True(t, time.Since(start) >= 40*time.Millisecond, "calls must wait for the limiter's delay")
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	err := invokeUnary(interceptor, ctx, pingMethod)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "calls must fail if the limiter's delay exceeds their deadline")
+	assert.True(t, time.Since(start) < 10*time.Millisecond, "calls must fail without waiting for their deadline")
+}
+
+func TestUnaryClientInterceptor_WaitCancelled(t *testing.T) {
+	bucket := NewTokenBucket(0.1, 1)
+	bucket.Limit()
+	ctx, cancel := context.WithCancel(context.TODO())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	err := invokeUnary(UnaryClientInterceptor(bucket), ctx, pingMethod)
+	assert.Equal(t, codes.Canceled, status.Code(err), "waiting calls must fail when cancelled")
+}
+
+// countingLimiter is a Limiter that is not a DelayLimiter, limiting its first n calls.
+type countingLimiter struct {
+	calls int32
+	n     int32
+}
+
+func (l *countingLimiter) Limit() bool {
+	return atomic.AddInt32(&l.calls, 1) <= l.n
+}
+
+func TestUnaryClientInterceptor_WaitPolls(t *testing.T) {
+	limiter := &countingLimiter{n: 2}
+	assert.NoError(t, invokeUnary(UnaryClientInterceptor(limiter), context.TODO(), pingMethod))
+	assert.EqualValues(t, 3, limiter.calls, "limiters without delays must be polled")
+}
+
+func TestUnaryClientInterceptor_FailFast(t *testing.T) {
+	interceptor := UnaryClientInterceptor(NewTokenBucket(0.1, 1), WithMode(FailFast), WithMethodMode("/mwitkow.testproto.TestService/PingList", Wait))
+	assert.NoError(t, invokeUnary(interceptor, context.TODO(), pingMethod))
+	err := invokeUnary(interceptor, context.TODO(), pingMethod)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "fail-fast calls must fail right away")
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+	err = invokeUnary(interceptor, ctx, "/mwitkow.testproto.TestService/PingList")
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Contains(t, err.Error(), "deadline", "per-method modes must override the default")
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	interceptor := StreamClientInterceptor(NewTokenBucket(0.1, 1), WithMode(FailFast))
+	open := func() error {
+		_, err := interceptor(context.TODO(), &grpc.StreamDesc{ServerStreams: true}, nil, pingMethod, func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return nil, nil
+		})
+		return err
+	}
+	assert.NoError(t, open())
+	assert.Equal(t, codes.ResourceExhausted, status.Code(open()), "opening streams must be limited")
+}
@@ -0,0 +1,320 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	grpc_middleware "github.com/rkollar/go-grpc-middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// NoLatency is released by calls without a meaningful latency, e.g. streams, whose duration depends on the
+// caller rather than on the load of the server.
+const NoLatency time.Duration = -1
+
+// ConcurrencyLimiter limits the number of calls in flight.
+type ConcurrencyLimiter interface {
+	// Acquire takes a slot for a call, and returns false if all slots are taken.
+	Acquire() bool
+	// Release frees the slot of a finished call, reporting how long it took, or NoLatency.
+	Release(latency time.Duration)
+}
+
+// FixedConcurrency is a ConcurrencyLimiter with a fixed number of slots.
+type FixedConcurrency struct {
+	mu       sync.Mutex
+	limit    int
+	inFlight int
+}
+
+// NewFixedConcurrency creates a FixedConcurrency allowing limit calls in flight.
+func NewFixedConcurrency(limit int) *FixedConcurrency {
+	if limit <= 0 {
+		panic(fmt.Sprintf("ratelimit: invalid concurrency limit %d", limit))
+	}
+	return &FixedConcurrency{limit: limit}
+}
+
+// Acquire takes a slot for a call.
+func (c *FixedConcurrency) Acquire() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.inFlight >= c.limit {
+		return false
+	}
+	c.inFlight++
+	return true
+}
+
+// Release frees the slot of a call.
+func (c *FixedConcurrency) Release(time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight--
+}
+
+// InFlight returns the number of calls in flight.
+func (c *FixedConcurrency) InFlight() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.inFlight
+}
+
+var defaultAdaptiveOptions = &adaptiveOptions{
+	initialLimit: 20,
+	minLimit:     1,
+	maxLimit:     1000,
+	backoff:      0.9,
+	tolerance:    1.5,
+}
+
+type adaptiveOptions struct {
+	initialLimit int
+	minLimit     int
+	maxLimit     int
+	backoff      float64
+	tolerance    float64
+}
+
+// AdaptiveOption configures the adaptive concurrency limiters.
+type AdaptiveOption func(*adaptiveOptions)
+
+func evaluateAdaptiveOptions(opts []AdaptiveOption) *adaptiveOptions {
+	optCopy := &adaptiveOptions{}
+	*optCopy = *defaultAdaptiveOptions
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+// WithLimits sets the initial limit of calls in flight, and the bounds the limit is adapted within. Defaults to
+// an initial limit of 20, within 1 and 1000.
+func WithLimits(initial, min, max int) AdaptiveOption {
+	return func(o *adaptiveOptions) {
+		o.initialLimit, o.minLimit, o.maxLimit = initial, min, max
+	}
+}
+
+// WithBackoff sets the ratio the AIMD limit is multiplied by when latency exceeds the threshold. Defaults to 0.9.
+func WithBackoff(ratio float64) AdaptiveOption {
+	return func(o *adaptiveOptions) {
+		o.backoff = ratio
+	}
+}
+
+// WithTolerance sets how many times the long-term latency the recent latency may grow to before the gradient
+// limit shrinks. Defaults to 1.5.
+func WithTolerance(ratio float64) AdaptiveOption {
+	return func(o *adaptiveOptions) {
+		o.tolerance = ratio
+	}
+}
+
+// adaptiveConcurrency is a ConcurrencyLimiter whose limit is adapted to the latencies of the calls.
+type adaptiveConcurrency struct {
+	mu       sync.Mutex
+	opts     *adaptiveOptions
+	limit    float64
+	inFlight int
+	// update adapts the limit to the latency of a call. It is called with mu held.
+	update func(latency time.Duration)
+}
+
+func newAdaptiveConcurrency(opts []AdaptiveOption) *adaptiveConcurrency {
+	o := evaluateAdaptiveOptions(opts)
+	if o.minLimit <= 0 || o.minLimit > o.initialLimit || o.initialLimit > o.maxLimit {
+		panic(fmt.Sprintf("ratelimit: invalid concurrency limits %d <= %d <= %d", o.minLimit, o.initialLimit, o.maxLimit))
+	}
+	return &adaptiveConcurrency{opts: o, limit: float64(o.initialLimit)}
+}
+
+// Acquire takes a slot for a call.
+func (c *adaptiveConcurrency) Acquire() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.inFlight >= int(c.limit) {
+		return false
+	}
+	c.inFlight++
+	return true
+}
+
+// Release frees the slot of a call, adapting the limit to its latency.
+func (c *adaptiveConcurrency) Release(latency time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight--
+	if latency < 0 {
+		return
+	}
+	c.update(latency)
+	c.limit = math.Max(float64(c.opts.minLimit), math.Min(float64(c.opts.maxLimit), c.limit))
+}
+
+// CurrentLimit returns the current limit of calls in flight.
+func (c *adaptiveConcurrency) CurrentLimit() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return int(c.limit)
+}
+
+// utilized returns whether the calls in flight use enough of the limit for their latency to tell whether the
+// limit could grow. It is called with mu held, after the call has been released.
+func (c *adaptiveConcurrency) utilized() bool {
+	return float64(c.inFlight+1) >= c.limit/2
+}
+
+// AIMDConcurrency is a ConcurrencyLimiter that adapts its limit by additive increase and multiplicative decrease:
+// the limit grows by one for each limit calls faster than the latency threshold, and shrinks by the backoff
+// ratio for each slower call.
+type AIMDConcurrency struct {
+	*adaptiveConcurrency
+}
+
+// NewAIMDConcurrency creates an AIMDConcurrency shrinking its limit for calls slower than latencyThreshold.
+func NewAIMDConcurrency(latencyThreshold time.Duration, opts ...AdaptiveOption) *AIMDConcurrency {
+	c := newAdaptiveConcurrency(opts)
+	c.update = func(latency time.Duration) {
+		if latency > latencyThreshold {
+			c.limit *= c.opts.backoff
+		} else if c.utilized() {
+			c.limit += 1 / c.limit
+		}
+	}
+	return &AIMDConcurrency{c}
+}
+
+// GradientConcurrency is a ConcurrencyLimiter that adapts its limit by the gradient of latency: the ratio of the
+// long-term average latency to the recent one. While latency is stable the limit grows, probing for more
+// capacity, and once recent latency exceeds the long-term one by the tolerance, the limit shrinks in proportion.
+type GradientConcurrency struct {
+	*adaptiveConcurrency
+	shortLatency float64
+	longLatency  float64
+}
+
+const (
+	// shortLatencyWeight and longLatencyWeight are the weights of each latency in the moving averages.
+	shortLatencyWeight = 0.1
+	longLatencyWeight  = 0.01
+	// gradientSmoothing is how much of a new limit is taken over at once.
+	gradientSmoothing = 0.2
+)
+
+// NewGradientConcurrency creates a GradientConcurrency.
+func NewGradientConcurrency(opts ...AdaptiveOption) *GradientConcurrency {
+	g := &GradientConcurrency{adaptiveConcurrency: newAdaptiveConcurrency(opts)}
+	g.update = g.updateLimit
+	return g
+}
+
+func (g *GradientConcurrency) updateLimit(latency time.Duration) {
+	sample := float64(latency)
+	if g.longLatency == 0 {
+		g.shortLatency, g.longLatency = sample, sample
+	}
+	g.shortLatency = g.shortLatency*(1-shortLatencyWeight) + sample*shortLatencyWeight
+	g.longLatency = g.longLatency*(1-longLatencyWeight) + sample*longLatencyWeight
+	// Once latency settles at a higher level, let the long-term average catch up, so that the limit can grow again.
+	if g.longLatency/g.shortLatency > 2 {
+		g.longLatency *= 0.95
+	}
+	if !g.utilized() {
+		return
+	}
+	gradient := math.Max(0.5, math.Min(1, g.opts.tolerance*g.longLatency/g.shortLatency))
+	// Allow a queue of the square root of the limit on top, so that the limit grows while latency is stable.
+	newLimit := g.limit*gradient + math.Sqrt(g.limit)
+	g.limit = g.limit*(1-gradientSmoothing) + newLimit*gradientSmoothing
+}
+
+type concurrencyOptions struct {
+	methodLimiters map[string]ConcurrencyLimiter
+}
+
+// ConcurrencyOption configures the concurrency limiting interceptors.
+type ConcurrencyOption func(*concurrencyOptions)
+
+func evaluateConcurrencyOptions(opts []ConcurrencyOption) *concurrencyOptions {
+	o := &concurrencyOptions{methodLimiters: map[string]ConcurrencyLimiter{}}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithMethodConcurrency additionally limits the calls of the method, e.g. `/mwitkow.testproto.TestService/Ping`,
+// by the limiter.
+func WithMethodConcurrency(fullMethod string, limiter ConcurrencyLimiter) ConcurrencyOption {
+	return func(o *concurrencyOptions) {
+		o.methodLimiters[fullMethod] = limiter
+	}
+}
+
+// ConcurrencyUnaryServerInterceptor returns a new unary server interceptor that limits the calls in flight by the
+// global limiter, and by the limiters of their methods. The global limiter may be nil to only limit methods.
+func ConcurrencyUnaryServerInterceptor(limiter ConcurrencyLimiter, opts ...ConcurrencyOption) grpc.UnaryServerInterceptor {
+	o := evaluateConcurrencyOptions(opts)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		release, err := o.acquire(limiter, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		startTime := time.Now()
+		defer func() {
+			release(time.Since(startTime))
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// ConcurrencyStreamServerInterceptor returns a new stream server interceptor that limits the streams in flight by
+// the global limiter, and by the limiters of their methods. Streams take their slot for their whole lifetime.
+func ConcurrencyStreamServerInterceptor(limiter ConcurrencyLimiter, opts ...ConcurrencyOption) grpc.StreamServerInterceptor {
+	o := evaluateConcurrencyOptions(opts)
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		release, err := o.acquire(limiter, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		defer release(NoLatency)
+		return handler(srv, stream)
+	}
+}
+
+// ConcurrencyMiddleware returns a new grpc_middleware.Middleware that limits the calls in flight on the server side.
+func ConcurrencyMiddleware(limiter ConcurrencyLimiter, opts ...ConcurrencyOption) grpc_middleware.Middleware {
+	return grpc_middleware.Middleware{
+		UnaryServer:  ConcurrencyUnaryServerInterceptor(limiter, opts...),
+		StreamServer: ConcurrencyStreamServerInterceptor(limiter, opts...),
+	}
+}
+
+// acquire takes the slots of the call from the limiter of its method and the global limiter, returning the
+// function releasing them.
+func (o *concurrencyOptions) acquire(limiter ConcurrencyLimiter, fullMethod string) (func(time.Duration), error) {
+	methodLimiter := o.methodLimiters[fullMethod]
+	if methodLimiter != nil && !methodLimiter.Acquire() {
+		return nil, status.Errorf(codes.ResourceExhausted, "%s is rejected by grpc_ratelimit middleware, too many calls in flight, please retry later.", fullMethod)
+	}
+	if limiter != nil && !limiter.Acquire() {
+		if methodLimiter != nil {
+			methodLimiter.Release(NoLatency)
+		}
+		return nil, status.Errorf(codes.ResourceExhausted, "%s is rejected by grpc_ratelimit middleware, too many calls in flight, please retry later.", fullMethod)
+	}
+	return func(latency time.Duration) {
+		if limiter != nil {
+			limiter.Release(latency)
+		}
+		if methodLimiter != nil {
+			methodLimiter.Release(latency)
+		}
+	}, nil
+}
@@ -0,0 +1,157 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	_ ConcurrencyLimiter = (*FixedConcurrency)(nil)
+	_ ConcurrencyLimiter = (*AIMDConcurrency)(nil)
+	_ ConcurrencyLimiter = (*GradientConcurrency)(nil)
+)
+
+func TestFixedConcurrency(t *testing.T) {
+	limiter := NewFixedConcurrency(2)
+	assert.True(t, limiter.Acquire())
+	assert.True(t, limiter.Acquire())
+	assert.False(t, limiter.Acquire(), "slots beyond the limit must not be taken")
+	limiter.Release(NoLatency)
+	assert.Equal(t, 1, limiter.InFlight())
+	assert.True(t, limiter.Acquire(), "released slots must be taken again")
+}
+
+// fillSlots takes all slots of the limiter, so that it is utilized, returning how many were taken.
+func fillSlots(limiter ConcurrencyLimiter) int {
+	n := 0
+	for limiter.Acquire() {
+		n++
+	}
+	return n
+}
+
+// releaseSamples releases a slot and takes it again for each latency.
+func releaseSamples(limiter ConcurrencyLimiter, latency time.Duration, n int) {
+	for i := 0; i < n; i++ {
+		limiter.Release(latency)
+		limiter.Acquire()
+	}
+}
+
+func TestAIMDConcurrency(t *testing.T) {
+	limiter := NewAIMDConcurrency(100*time.Millisecond, WithLimits(10, 2, 20))
+	require.Equal(t, 10, fillSlots(limiter))
+
+	releaseSamples(limiter, 10*time.Millisecond, 12)
+	assert.Equal(t, 11, limiter.CurrentLimit(), "limit must grow by one for each limit fast calls")
+	releaseSamples(limiter, time.Second, 3)
+	assert.Equal(t, 8, limiter.CurrentLimit(), "limit must shrink multiplicatively for slow calls")
+	releaseSamples(limiter, time.Second, 100)
+	assert.Equal(t, 2, limiter.CurrentLimit(), "limit must not shrink below the minimum")
+	releaseSamples(limiter, NoLatency, 100)
+	assert.Equal(t, 2, limiter.CurrentLimit(), "calls without latency must not change the limit")
+}
+
+func TestAIMDConcurrency_NotUtilized(t *testing.T) {
+	limiter := NewAIMDConcurrency(100*time.Millisecond, WithLimits(10, 2, 20))
+	for i := 0; i < 100; i++ {
+		limiter.Acquire()
+		limiter.Release(10 * time.Millisecond)
+	}
+	assert.Equal(t, 10, limiter.CurrentLimit(), "limit must not grow while it is not used")
+}
+
+func TestGradientConcurrency(t *testing.T) {
+	limiter := NewGradientConcurrency(WithLimits(10, 2, 100))
+	fillSlots(limiter)
+
+	releaseSamples(limiter, 10*time.Millisecond, 50)
+	grown := limiter.CurrentLimit()
+	assert.True(t, grown > 10, "limit must grow while latency is stable, got %d", grown)
+
+	fillSlots(limiter)
+	releaseSamples(limiter, 100*time.Millisecond, 20)
+	assert.True(t, limiter.CurrentLimit() < grown, "limit must shrink when latency degrades, got %d", limiter.CurrentLimit())
+	releaseSamples(limiter, time.Second, 100)
+	assert.True(t, limiter.CurrentLimit() >= 2, "limit must not shrink below the minimum")
+}
+
+func TestAdaptiveConcurrency_InvalidLimits(t *testing.T) {
+	assert.Panics(t, func() { NewAIMDConcurrency(time.Second, WithLimits(1, 2, 3)) })
+	assert.Panics(t, func() { NewGradientConcurrency(WithLimits(10, 0, 20)) })
+	assert.Panics(t, func() { NewFixedConcurrency(0) })
+}
+
+func TestConcurrencyUnaryServerInterceptor(t *testing.T) {
+	interceptor := ConcurrencyUnaryServerInterceptor(NewFixedConcurrency(3), WithMethodConcurrency(pingMethod, NewFixedConcurrency(1)))
+	entered, release := make(chan struct{}), make(chan struct{})
+	call := func(method string, block bool) error {
+		_, err := interceptor(context.TODO(), "request", &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			if block {
+				entered <- struct{}{}
+				<-release
+			}
+			return "response", nil
+		})
+		return err
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); assert.NoError(t, call(pingMethod, true)) }()
+	go func() { defer wg.Done(); assert.NoError(t, call("/mwitkow.testproto.TestService/PingList", true)) }()
+	<-entered
+	<-entered
+	assert.Equal(t, codes.ResourceExhausted, status.Code(call(pingMethod, false)), "per-method limits must apply")
+	assert.NoError(t, call("/mwitkow.testproto.TestService/PingError", false), "other methods must only be limited globally")
+
+	wg.Add(1)
+	go func() { defer wg.Done(); assert.NoError(t, call("/mwitkow.testproto.TestService/PingError", true)) }()
+	<-entered
+	assert.Equal(t, codes.ResourceExhausted, status.Code(call("/mwitkow.testproto.TestService/PingError", false)), "global limit must apply")
+	close(release)
+	wg.Wait()
+	assert.NoError(t, call(pingMethod, false), "slots must be released when calls finish")
+}
+
+func TestConcurrencyStreamServerInterceptor(t *testing.T) {
+	limiter := NewFixedConcurrency(1)
+	interceptor := ConcurrencyStreamServerInterceptor(limiter)
+	var nested error
+	err := interceptor(nil, &fakeServerStream{ctx: context.TODO()}, &grpc.StreamServerInfo{FullMethod: pingMethod}, func(srv interface{}, stream grpc.ServerStream) error {
+		nested = interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: pingMethod}, func(srv interface{}, stream grpc.ServerStream) error {
+			return nil
+		})
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(nested), "streams must hold their slot for their lifetime")
+	assert.Equal(t, 0, limiter.InFlight(), "streams must release their slot when they finish")
+}
+
+func BenchmarkConcurrencyLimiters(b *testing.B) {
+	for name, limiter := range map[string]ConcurrencyLimiter{
+		"Fixed":    NewFixedConcurrency(1 << 30),
+		"AIMD":     NewAIMDConcurrency(time.Second, WithLimits(1<<20, 1, 1<<30)),
+		"Gradient": NewGradientConcurrency(WithLimits(1<<20, 1, 1<<30)),
+	} {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if limiter.Acquire() {
+						limiter.Release(time.Millisecond)
+					}
+				}
+			})
+		})
+	}
+}
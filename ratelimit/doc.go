@@ -5,6 +5,32 @@ Server Side Ratelimit Middleware
 
 It allows to do grpc rate limit by your own rate limiter (e.g. token bucket, leaky bucket, etc.)
 
+The package ships the common limiters: `TokenBucket`, `LeakyBucket`, `FixedWindow` and `SlidingWindow`.
+They are safe for concurrent use, and their clock can be replaced with `WithClock` for deterministic tests.
+
+A `Limiter` applies a single limit to all calls. To limit callers separately, use a `KeyedLimiter` with the
+`Keyed` interceptors instead. `KeyedBuckets` keeps a limiter per key, e.g. per peer IP, metadata header,
+authenticated principal or `grpc_ctxtags` value, and evicts the limiters of idle keys and, with `WithMaxKeys`,
+of the least recently used keys.
+
+`StreamServerInterceptor` only limits opening streams. `MessageStreamServerInterceptor` limits the messages
+received on each stream by a limiter of its own, and with `WithSendLimit` the messages sent too. Messages beyond
+the limit are delayed by default, or abort the stream with `ResourceExhausted` with `WithMessageMode(FailFast)`.
+
+Concurrency Limiting Middleware
+
+The `Concurrency` interceptors limit the calls in flight rather than their rate, rejecting calls beyond the limit
+with `ResourceExhausted`, globally and per method with `WithMethodConcurrency`. Streams hold their slot for their
+whole lifetime. Besides `FixedConcurrency`, `AIMDConcurrency` and `GradientConcurrency` adapt their limit to the
+observed latency of unary calls, shrinking it when the server slows down and growing it back as it recovers.
+
+Client Side Ratelimit Middleware
+
+`UnaryClientInterceptor` and `StreamClientInterceptor` throttle outgoing calls, e.g. of batch jobs calling
+partner APIs. By default calls exceeding the limit wait until the limiter lets them through, and fail early
+with `ResourceExhausted` if that would take longer than their deadline. With `FailFast` mode they fail right
+away instead. The mode can be set per method with `WithMethodMode`.
+
 Please see examples for simple examples of use.
 */
 package ratelimit
@@ -0,0 +1,186 @@
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"github.com/rkollar/go-grpc-middleware/tags"
+	"github.com/rkollar/go-grpc-middleware/util/metautils"
+	"google.golang.org/grpc/peer"
+)
+
+// KeyedLimiter defines the interface to perform request rate limiting with the knowledge of the call, e.g. to
+// limit each caller separately. If Limit returns true, the request will be rejected.
+//
+// The request is nil for streams, as they are limited before their first message is received.
+type KeyedLimiter interface {
+	Limit(ctx context.Context, fullMethod string, req interface{}) bool
+}
+
+// Global adapts a Limiter into a KeyedLimiter that applies a single limit to all calls.
+func Global(limiter Limiter) KeyedLimiter {
+	return globalLimiter{limiter}
+}
+
+type globalLimiter struct {
+	limiter Limiter
+}
+
+func (g globalLimiter) Limit(context.Context, string, interface{}) bool {
+	return g.limiter.Limit()
+}
+
+// KeyFunc returns the key of a call, by which calls are limited separately. Calls for which the key cannot be
+// determined should return an empty key, so that they share a limit.
+type KeyFunc func(ctx context.Context, fullMethod string, req interface{}) string
+
+// MethodKey keys calls by their full method name.
+func MethodKey() KeyFunc {
+	return func(_ context.Context, fullMethod string, _ interface{}) string {
+		return fullMethod
+	}
+}
+
+// PeerIPKey keys calls by the IP address of the peer.
+func PeerIPKey() KeyFunc {
+	return func(ctx context.Context, _ string, _ interface{}) string {
+		p, ok := peer.FromContext(ctx)
+		if !ok || p.Addr == nil {
+			return ""
+		}
+		if tcpAddr, ok := p.Addr.(*net.TCPAddr); ok {
+			return tcpAddr.IP.String()
+		}
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return host
+		}
+		return p.Addr.String()
+	}
+}
+
+// MetadataKey keys calls by the value of the metadata header, e.g. `x-tenant-id`.
+func MetadataKey(header string) KeyFunc {
+	return func(ctx context.Context, _ string, _ interface{}) string {
+		return metautils.ExtractIncoming(ctx).Get(header)
+	}
+}
+
+// PrincipalKey keys calls by the name of their grpc_auth.Principal. The rate limiting middleware must run after
+// the auth middleware.
+func PrincipalKey() KeyFunc {
+	return func(ctx context.Context, _ string, _ interface{}) string {
+		if principal, ok := grpc_auth.PrincipalFromContext(ctx); ok {
+			return principal.Name
+		}
+		return ""
+	}
+}
+
+// TagKey keys calls by the value of the grpc_ctxtags tag, e.g. one set by request field extraction.
+func TagKey(tag string) KeyFunc {
+	return func(ctx context.Context, _ string, _ interface{}) string {
+		if value, ok := grpc_ctxtags.Extract(ctx).Values()[tag]; ok {
+			return fmt.Sprint(value)
+		}
+		return ""
+	}
+}
+
+// CombineKeys keys calls by all of the keys, e.g. by tenant and method.
+func CombineKeys(keyFuncs ...KeyFunc) KeyFunc {
+	return func(ctx context.Context, fullMethod string, req interface{}) string {
+		keys := make([]string, len(keyFuncs))
+		for i, f := range keyFuncs {
+			keys[i] = f(ctx, fullMethod, req)
+		}
+		return strings.Join(keys, "\x00")
+	}
+}
+
+// KeyedBuckets is a KeyedLimiter that limits calls by a separate Limiter for each key, e.g. a TokenBucket per peer.
+//
+// Limiters of keys that have not been used for the idle timeout are evicted, so that keys of past callers do not
+// accumulate. The idle timeout should be long enough for evicted limiters to have recovered, e.g. for a token
+// bucket to have refilled, otherwise eviction resets their limits early.
+//
+// As keys are usually chosen by the caller, e.g. with MetadataKey, use WithMaxKeys to bound the number of limiters.
+// Once it is reached, the limiter of the least recently used key is evicted for a new one.
+type KeyedBuckets struct {
+	keyFunc     KeyFunc
+	newLimiter  func() Limiter
+	idleTimeout time.Duration
+	maxKeys     int
+	now         func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	lru     *list.List
+}
+
+type keyedBucket struct {
+	key      string
+	limiter  Limiter
+	lastUsed time.Time
+}
+
+// NewKeyedBuckets creates KeyedBuckets that key calls by keyFunc, creating their limiters with newLimiter.
+// It panics if the idle timeout is not positive.
+func NewKeyedBuckets(keyFunc KeyFunc, newLimiter func() Limiter, idleTimeout time.Duration, opts ...LimiterOption) *KeyedBuckets {
+	if idleTimeout <= 0 {
+		panic(fmt.Sprintf("ratelimit: invalid keyed buckets idle timeout %v", idleTimeout))
+	}
+	o := evaluateLimiterOptions(opts)
+	return &KeyedBuckets{
+		keyFunc:     keyFunc,
+		newLimiter:  newLimiter,
+		idleTimeout: idleTimeout,
+		maxKeys:     o.maxKeys,
+		now:         o.now,
+		buckets:     map[string]*list.Element{},
+		lru:         list.New(),
+	}
+}
+
+// Limit limits the call by the limiter of its key.
+func (k *KeyedBuckets) Limit(ctx context.Context, fullMethod string, req interface{}) bool {
+	key := k.keyFunc(ctx, fullMethod, req)
+	now := k.now()
+	k.mu.Lock()
+	// The least recently used keys are at the back, so idle ones are evicted from there.
+	for e := k.lru.Back(); e != nil && now.Sub(e.Value.(*keyedBucket).lastUsed) >= k.idleTimeout; e = k.lru.Back() {
+		k.remove(e)
+	}
+	e, ok := k.buckets[key]
+	if ok {
+		k.lru.MoveToFront(e)
+	} else {
+		if k.maxKeys > 0 && k.lru.Len() >= k.maxKeys {
+			k.remove(k.lru.Back())
+		}
+		e = k.lru.PushFront(&keyedBucket{key: key, limiter: k.newLimiter()})
+		k.buckets[key] = e
+	}
+	b := e.Value.(*keyedBucket)
+	b.lastUsed = now
+	k.mu.Unlock()
+	// Limiters synchronize themselves, so that calls of different keys do not wait for each other.
+	return b.limiter.Limit()
+}
+
+func (k *KeyedBuckets) remove(e *list.Element) {
+	k.lru.Remove(e)
+	delete(k.buckets, e.Value.(*keyedBucket).key)
+}
+
+// Len returns the number of keys with a limiter.
+func (k *KeyedBuckets) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.buckets)
+}
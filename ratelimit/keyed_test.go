@@ -0,0 +1,141 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"github.com/rkollar/go-grpc-middleware/tags"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const pingMethod = "/mwitkow.testproto.TestService/Ping"
+
+func TestKeyFuncs(t *testing.T) {
+	ctx := peer.NewContext(context.TODO(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 4321}})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-tenant-id", "acme"))
+	ctx = grpc_auth.ContextWithPrincipal(ctx, &grpc_auth.Principal{Name: "alice"})
+	ctx = grpc_ctxtags.SetInContext(ctx, grpc_ctxtags.NewTags().Set("grpc.request.user_id", 42))
+
+	assert.Equal(t, pingMethod, MethodKey()(ctx, pingMethod, nil))
+	assert.Equal(t, "10.0.0.1", PeerIPKey()(ctx, pingMethod, nil), "peer IP must not include the port")
+	assert.Equal(t, "acme", MetadataKey("x-tenant-id")(ctx, pingMethod, nil))
+	assert.Equal(t, "alice", PrincipalKey()(ctx, pingMethod, nil))
+	assert.Equal(t, "42", TagKey("grpc.request.user_id")(ctx, pingMethod, nil))
+	assert.Equal(t, "acme\x00"+pingMethod, CombineKeys(MetadataKey("x-tenant-id"), MethodKey())(ctx, pingMethod, nil))
+
+	for name, keyFunc := range map[string]KeyFunc{
+		"peer IP":   PeerIPKey(),
+		"metadata":  MetadataKey("x-tenant-id"),
+		"principal": PrincipalKey(),
+		"tag":       TagKey("grpc.request.user_id"),
+	} {
+		assert.Equal(t, "", keyFunc(context.TODO(), pingMethod, nil), "%s key must be empty if unknown", name)
+	}
+}
+
+func TestKeyedBuckets(t *testing.T) {
+	clock := newFakeClock()
+	buckets := NewKeyedBuckets(MetadataKey("x-tenant-id"), func() Limiter {
+		return NewFixedWindow(2, time.Hour, WithClock(clock.Now))
+	}, time.Minute, WithClock(clock.Now))
+	tenant := func(id string) context.Context {
+		return metadata.NewIncomingContext(context.TODO(), metadata.Pairs("x-tenant-id", id))
+	}
+	limit := func(id string) bool {
+		return buckets.Limit(tenant(id), pingMethod, nil)
+	}
+
+	assert.False(t, limit("acme"))
+	assert.False(t, limit("acme"))
+	assert.True(t, limit("acme"), "keys must be limited by their limiter")
+	assert.False(t, limit("globex"), "keys must be limited separately")
+	assert.Equal(t, 2, buckets.Len())
+
+	clock.Advance(30 * time.Second)
+	assert.False(t, limit("globex"))
+	clock.Advance(30 * time.Second)
+	assert.True(t, limit("globex"), "recently used keys must not be evicted")
+	assert.Equal(t, 1, buckets.Len(), "idle keys must be evicted")
+	assert.False(t, limit("acme"), "evicted keys must get a new limiter")
+}
+
+func TestKeyedBuckets_MaxKeys(t *testing.T) {
+	buckets := NewKeyedBuckets(MetadataKey("x-tenant-id"), func() Limiter {
+		return NewFixedWindow(1, time.Hour)
+	}, time.Hour, WithMaxKeys(2))
+	limit := func(id string) bool {
+		return buckets.Limit(metadata.NewIncomingContext(context.TODO(), metadata.Pairs("x-tenant-id", id)), pingMethod, nil)
+	}
+
+	assert.False(t, limit("acme"))
+	assert.False(t, limit("globex"))
+	assert.True(t, limit("acme"), "keys must be limited by their limiter")
+	for i := 0; i < 100; i++ {
+		limit(fmt.Sprintf("rotated-%d", i))
+		assert.True(t, buckets.Len() <= 2, "number of keys must be bounded")
+	}
+	assert.False(t, limit("globex"), "least recently used keys must be evicted")
+}
+
+func TestKeyedBuckets_InvalidIdleTimeout(t *testing.T) {
+	newLimiter := func() Limiter { return NewTokenBucket(1, 1) }
+	assert.Panics(t, func() { NewKeyedBuckets(MethodKey(), newLimiter, 0) }, "idle timeouts that evict every key on each call must be rejected")
+	assert.Panics(t, func() { NewKeyedBuckets(MethodKey(), newLimiter, -time.Second) })
+}
+
+func TestKeyedUnaryServerInterceptor(t *testing.T) {
+	buckets := NewKeyedBuckets(PrincipalKey(), func() Limiter { return NewTokenBucket(1, 1) }, time.Minute)
+	interceptor := KeyedUnaryServerInterceptor(buckets)
+	call := func(user string) error {
+		ctx := grpc_auth.ContextWithPrincipal(context.TODO(), &grpc_auth.Principal{Name: user})
+		_, err := interceptor(ctx, "request", &grpc.UnaryServerInfo{FullMethod: pingMethod}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return "response", nil
+		})
+		return err
+	}
+	assert.NoError(t, call("alice"))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(call("alice")), "limited calls must fail with ResourceExhausted")
+	assert.NoError(t, call("bob"), "other principals must not be limited")
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (f *fakeServerStream) Context() context.Context {
+	return f.ctx
+}
+
+func TestKeyedStreamServerInterceptor(t *testing.T) {
+	interceptor := KeyedStreamServerInterceptor(Global(&mockFailLimiter{}))
+	err := interceptor(nil, &fakeServerStream{ctx: context.TODO()}, &grpc.StreamServerInfo{FullMethod: pingMethod}, func(srv interface{}, stream grpc.ServerStream) error {
+		return nil
+	})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "global limiters must work as keyed ones")
+}
+
+func BenchmarkKeyedBuckets(b *testing.B) {
+	buckets := NewKeyedBuckets(MetadataKey("x-tenant-id"), func() Limiter { return NewTokenBucket(1e9, 1000) }, time.Minute)
+	ctxs := make([]context.Context, 100)
+	for i := range ctxs {
+		ctxs[i] = metadata.NewIncomingContext(context.TODO(), metadata.Pairs("x-tenant-id", string(rune('a'+i%26))+string(rune('a'+i/26))))
+	}
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			buckets.Limit(ctxs[i%len(ctxs)], pingMethod, nil)
+			i++
+		}
+	})
+}
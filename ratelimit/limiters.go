@@ -0,0 +1,274 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// DelayLimiter is a Limiter that can tell how long until it would let a request through, so that clients can
+// wait for it efficiently. All built-in limiters implement it.
+type DelayLimiter interface {
+	Limiter
+	// Delay returns how long until the next call of Limit would return false, or zero if it would now.
+	Delay() time.Duration
+}
+
+type limiterOptions struct {
+	now     func() time.Time
+	maxKeys int
+}
+
+// LimiterOption configures the built-in limiters.
+type LimiterOption func(*limiterOptions)
+
+func evaluateLimiterOptions(opts []LimiterOption) *limiterOptions {
+	o := &limiterOptions{now: time.Now}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithClock sets the clock of the limiter, e.g. to a fake one in tests. Defaults to time.Now.
+func WithClock(now func() time.Time) LimiterOption {
+	return func(o *limiterOptions) {
+		o.now = now
+	}
+}
+
+// WithMaxKeys bounds the number of keys of KeyedBuckets, evicting the least recently used key for a new one
+// once it is reached. Defaults to no bound.
+func WithMaxKeys(n int) LimiterOption {
+	return func(o *limiterOptions) {
+		o.maxKeys = n
+	}
+}
+
+// TokenBucket is a Limiter that allows bursts of up to burst requests, refilled at a constant rate.
+type TokenBucket struct {
+	mu     sync.Mutex
+	now    func() time.Time
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates a full TokenBucket refilled with rate tokens per second.
+func NewTokenBucket(rate float64, burst int, opts ...LimiterOption) *TokenBucket {
+	if rate <= 0 || burst <= 0 {
+		panic(fmt.Sprintf("ratelimit: invalid token bucket rate %v or burst %d", rate, burst))
+	}
+	o := evaluateLimiterOptions(opts)
+	return &TokenBucket{now: o.now, rate: rate, burst: float64(burst), tokens: float64(burst), last: o.now()}
+}
+
+// Limit takes a token from the bucket, and returns true if there is none left.
+func (b *TokenBucket) Limit() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+	if b.tokens < 1 {
+		return true
+	}
+	b.tokens--
+	return false
+}
+
+// LeakyBucket is a Limiter that lets requests through at a constant rate, tolerating bursts of up to capacity
+// requests. Unlike a TokenBucket, which allows a burst whenever it is full, it spaces requests evenly once
+// the bucket is full.
+//
+// It is implemented as the generic cell rate algorithm, i.e. a leaky bucket used as a meter.
+type LeakyBucket struct {
+	mu        sync.Mutex
+	now       func() time.Time
+	interval  time.Duration
+	tolerance time.Duration
+	// tat is the theoretical arrival time of the next request if requests arrived exactly at the rate.
+	tat time.Time
+}
+
+// NewLeakyBucket creates an empty LeakyBucket leaking rate requests per second.
+func NewLeakyBucket(rate float64, capacity int, opts ...LimiterOption) *LeakyBucket {
+	if rate <= 0 || capacity <= 0 {
+		panic(fmt.Sprintf("ratelimit: invalid leaky bucket rate %v or capacity %d", rate, capacity))
+	}
+	o := evaluateLimiterOptions(opts)
+	interval := time.Duration(float64(time.Second) / rate)
+	return &LeakyBucket{now: o.now, interval: interval, tolerance: time.Duration(capacity-1) * interval}
+}
+
+// Limit adds the request to the bucket, and returns true if it would overflow.
+func (b *LeakyBucket) Limit() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	tat := b.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	if tat.Sub(now) > b.tolerance {
+		return true
+	}
+	b.tat = tat.Add(b.interval)
+	return false
+}
+
+// FixedWindow is a Limiter that allows limit requests in each window of time, aligned to multiples of the window.
+//
+// It is the cheapest limiter, but allows up to twice the limit around the boundary of two windows.
+type FixedWindow struct {
+	mu     sync.Mutex
+	now    func() time.Time
+	limit  int
+	window time.Duration
+	start  time.Time
+	count  int
+}
+
+// NewFixedWindow creates a FixedWindow allowing limit requests per window.
+func NewFixedWindow(limit int, window time.Duration, opts ...LimiterOption) *FixedWindow {
+	if limit <= 0 || window <= 0 {
+		panic(fmt.Sprintf("ratelimit: invalid fixed window limit %d or window %v", limit, window))
+	}
+	o := evaluateLimiterOptions(opts)
+	return &FixedWindow{now: o.now, limit: limit, window: window}
+}
+
+// Limit counts the request in the current window, and returns true if the window's limit is reached.
+func (w *FixedWindow) Limit() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if start := w.now().Truncate(w.window); !start.Equal(w.start) {
+		w.start, w.count = start, 0
+	}
+	if w.count >= w.limit {
+		return true
+	}
+	w.count++
+	return false
+}
+
+// SlidingWindow is a Limiter that allows limit requests in any window of time.
+//
+// It approximates the requests in the window ending now by the requests of the current fixed window, plus the
+// requests of the previous one weighted by how much of it overlaps the sliding window, assuming they were
+// evenly spread. This avoids the bursts a FixedWindow allows at window boundaries in constant memory.
+type SlidingWindow struct {
+	mu        sync.Mutex
+	now       func() time.Time
+	limit     float64
+	window    time.Duration
+	start     time.Time
+	count     int
+	prevCount int
+}
+
+// NewSlidingWindow creates a SlidingWindow allowing limit requests per window.
+func NewSlidingWindow(limit int, window time.Duration, opts ...LimiterOption) *SlidingWindow {
+	if limit <= 0 || window <= 0 {
+		panic(fmt.Sprintf("ratelimit: invalid sliding window limit %d or window %v", limit, window))
+	}
+	o := evaluateLimiterOptions(opts)
+	return &SlidingWindow{now: o.now, limit: float64(limit), window: window}
+}
+
+// Limit counts the request in the current window, and returns true if the limit of the sliding window is reached.
+func (w *SlidingWindow) Limit() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.now()
+	if start := now.Truncate(w.window); !start.Equal(w.start) {
+		if start.Sub(w.start) == w.window {
+			w.prevCount = w.count
+		} else {
+			w.prevCount = 0
+		}
+		w.start, w.count = start, 0
+	}
+	overlap := float64(w.window-now.Sub(w.start)) / float64(w.window)
+	if float64(w.prevCount)*overlap+float64(w.count) >= w.limit {
+		return true
+	}
+	w.count++
+	return false
+}
+
+// Delay returns how long until the bucket has a token.
+func (b *TokenBucket) Delay() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	tokens := b.tokens
+	if elapsed := b.now().Sub(b.last); elapsed > 0 {
+		tokens += elapsed.Seconds() * b.rate
+	}
+	if tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - tokens) / b.rate * float64(time.Second))
+}
+
+// Delay returns how long until the bucket can take another request.
+func (b *LeakyBucket) Delay() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	if !b.tat.After(now) {
+		return 0
+	}
+	if excess := b.tat.Sub(now) - b.tolerance; excess > 0 {
+		return excess
+	}
+	return 0
+}
+
+// Delay returns how long until the next window, if the limit of the current one is reached.
+func (w *FixedWindow) Delay() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.now()
+	if start := now.Truncate(w.window); !start.Equal(w.start) || w.count < w.limit {
+		return 0
+	}
+	return w.start.Add(w.window).Sub(now)
+}
+
+// Delay returns how long until enough requests of the previous windows have slid out of the window.
+func (w *SlidingWindow) Delay() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.now()
+	start, count, prevCount := now.Truncate(w.window), float64(w.count), float64(w.prevCount)
+	if !start.Equal(w.start) {
+		if start.Sub(w.start) == w.window {
+			prevCount = count
+		} else {
+			prevCount = 0
+		}
+		count = 0
+	}
+	elapsed := float64(now.Sub(start)) / float64(w.window)
+	if prevCount*(1-elapsed)+count < w.limit {
+		return 0
+	}
+	var windows float64
+	if count < w.limit {
+		// The weighted requests of the previous window drop below the remaining limit within the current window.
+		windows = 1 - (w.limit-count)/prevCount - elapsed
+	} else {
+		// The requests of the current window drop below the limit within the next one.
+		windows = 1 - elapsed + 1 - w.limit/count
+	}
+	// The estimate must drop below the limit, rather than reach it, so wait a bit longer.
+	return time.Duration(math.Ceil(windows*float64(w.window))) + time.Nanosecond
+}
@@ -0,0 +1,159 @@
+package ratelimit
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	_ Limiter = (*TokenBucket)(nil)
+	_ Limiter = (*LeakyBucket)(nil)
+	_ Limiter = (*FixedWindow)(nil)
+	_ Limiter = (*SlidingWindow)(nil)
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	// Start at a whole second, so that windows are aligned with the steps of the tests.
+	return &fakeClock{now: time.Unix(1600000020, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// allowed returns how many of n requests the limiter lets through.
+func allowed(l Limiter, n int) int {
+	passed := 0
+	for i := 0; i < n; i++ {
+		if !l.Limit() {
+			passed++
+		}
+	}
+	return passed
+}
+
+func TestTokenBucket(t *testing.T) {
+	clock := newFakeClock()
+	bucket := NewTokenBucket(10, 5, WithClock(clock.Now))
+
+	assert.Equal(t, 5, allowed(bucket, 10), "full bucket must allow a burst")
+	clock.Advance(100 * time.Millisecond)
+	assert.Equal(t, 1, allowed(bucket, 10), "bucket must be refilled at the rate")
+	clock.Advance(50 * time.Millisecond)
+	assert.Equal(t, 0, allowed(bucket, 10), "partial tokens must not be taken")
+	clock.Advance(50 * time.Millisecond)
+	assert.Equal(t, 1, allowed(bucket, 10), "partial tokens must accumulate")
+	clock.Advance(time.Hour)
+	assert.Equal(t, 5, allowed(bucket, 10), "bucket must not be refilled beyond the burst")
+}
+
+func TestLeakyBucket(t *testing.T) {
+	clock := newFakeClock()
+	bucket := NewLeakyBucket(10, 3, WithClock(clock.Now))
+
+	assert.Equal(t, 3, allowed(bucket, 10), "empty bucket must tolerate its capacity")
+	clock.Advance(100 * time.Millisecond)
+	assert.Equal(t, 1, allowed(bucket, 10), "bucket must leak at the rate")
+	for i := 0; i < 10; i++ {
+		clock.Advance(100 * time.Millisecond)
+		assert.Equal(t, 1, allowed(bucket, 10), "full bucket must let requests through evenly")
+	}
+	clock.Advance(time.Hour)
+	assert.Equal(t, 3, allowed(bucket, 10), "drained bucket must not tolerate more than its capacity")
+}
+
+func TestFixedWindow(t *testing.T) {
+	clock := newFakeClock()
+	window := NewFixedWindow(5, time.Second, WithClock(clock.Now))
+
+	assert.Equal(t, 5, allowed(window, 10))
+	clock.Advance(999 * time.Millisecond)
+	assert.Equal(t, 0, allowed(window, 10), "limit must apply to the whole window")
+	clock.Advance(time.Millisecond)
+	assert.Equal(t, 5, allowed(window, 10), "limit must be reset in the next window")
+}
+
+func TestSlidingWindow(t *testing.T) {
+	clock := newFakeClock()
+	window := NewSlidingWindow(10, time.Second, WithClock(clock.Now))
+
+	clock.Advance(500 * time.Millisecond)
+	assert.Equal(t, 10, allowed(window, 20))
+	clock.Advance(500 * time.Millisecond)
+	assert.Equal(t, 0, allowed(window, 20), "requests of the previous window must count at its boundary")
+	clock.Advance(500 * time.Millisecond)
+	assert.Equal(t, 5, allowed(window, 20), "requests of the previous window must count by their overlap")
+	clock.Advance(2 * time.Second)
+	assert.Equal(t, 10, allowed(window, 20), "requests of older windows must not count")
+}
+
+func TestLimiters_Concurrent(t *testing.T) {
+	clock := newFakeClock()
+	for name, limiter := range map[string]Limiter{
+		"TokenBucket":   NewTokenBucket(1, 100, WithClock(clock.Now)),
+		"LeakyBucket":   NewLeakyBucket(1, 100, WithClock(clock.Now)),
+		"FixedWindow":   NewFixedWindow(100, time.Second, WithClock(clock.Now)),
+		"SlidingWindow": NewSlidingWindow(100, time.Second, WithClock(clock.Now)),
+	} {
+		var passed int32
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				atomic.AddInt32(&passed, int32(allowed(limiter, 50)))
+			}()
+		}
+		wg.Wait()
+		assert.EqualValues(t, 100, passed, "%s must let exactly its limit through under concurrency", name)
+	}
+}
+
+func TestLimiters_InvalidParameters(t *testing.T) {
+	assert.Panics(t, func() { NewTokenBucket(0, 1) })
+	assert.Panics(t, func() { NewLeakyBucket(1, 0) })
+	assert.Panics(t, func() { NewFixedWindow(0, time.Second) })
+	assert.Panics(t, func() { NewSlidingWindow(1, 0) })
+}
+
+func BenchmarkLimiters(b *testing.B) {
+	for _, bench := range []struct {
+		name    string
+		limiter Limiter
+	}{
+		{"TokenBucket", NewTokenBucket(1e9, 1000)},
+		{"LeakyBucket", NewLeakyBucket(1e9, 1000)},
+		{"FixedWindow", NewFixedWindow(1e9, time.Second)},
+		{"SlidingWindow", NewSlidingWindow(1e9, time.Second)},
+	} {
+		for _, parallel := range []bool{false, true} {
+			b.Run(fmt.Sprintf("%s/parallel=%v", bench.name, parallel), func(b *testing.B) {
+				b.ReportAllocs()
+				if !parallel {
+					for i := 0; i < b.N; i++ {
+						bench.limiter.Limit()
+					}
+					return
+				}
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						bench.limiter.Limit()
+					}
+				})
+			})
+		}
+	}
+}
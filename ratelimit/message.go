@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"google.golang.org/grpc"
+)
+
+type messageOptions struct {
+	mode           Mode
+	newSendLimiter func() Limiter
+}
+
+// MessageOption configures the per-stream message rate limiting.
+type MessageOption func(*messageOptions)
+
+func evaluateMessageOptions(opts []MessageOption) *messageOptions {
+	o := &messageOptions{mode: Wait}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithMessageMode sets what is done with messages exceeding the limit. Defaults to Wait, which delays receiving
+// them, so that flow control pushes back on the caller. FailFast aborts the stream with `ResourceExhausted`.
+func WithMessageMode(mode Mode) MessageOption {
+	return func(o *messageOptions) {
+		o.mode = mode
+	}
+}
+
+// WithSendLimit additionally limits the messages sent on each stream by a limiter of its own, created by
+// newLimiter.
+func WithSendLimit(newLimiter func() Limiter) MessageOption {
+	return func(o *messageOptions) {
+		o.newSendLimiter = newLimiter
+	}
+}
+
+// MessageStreamServerInterceptor returns a new stream server interceptor that limits the rate of the messages
+// received on each stream by a limiter of its own, created by newLimiter when the stream opens.
+//
+// Unlike StreamServerInterceptor, which only limits opening streams, it keeps a single long-lived stream from
+// flooding the server with messages. Use it together with the selector package to limit selected methods only.
+func MessageStreamServerInterceptor(newLimiter func() Limiter, opts ...MessageOption) grpc.StreamServerInterceptor {
+	o := evaluateMessageOptions(opts)
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, o.wrap(stream, info.FullMethod, newLimiter()))
+	}
+}
+
+// LimitServerStream wraps the stream of the method, limiting the rate of the messages it receives by the limiter.
+// It is what MessageStreamServerInterceptor does for each stream, for handlers that limit their streams themselves.
+func LimitServerStream(stream grpc.ServerStream, fullMethod string, limiter Limiter, opts ...MessageOption) grpc.ServerStream {
+	return evaluateMessageOptions(opts).wrap(stream, fullMethod, limiter)
+}
+
+func (o *messageOptions) wrap(stream grpc.ServerStream, fullMethod string, recvLimiter Limiter) grpc.ServerStream {
+	var sendLimiter Limiter
+	if o.newSendLimiter != nil {
+		sendLimiter = o.newSendLimiter()
+	}
+	return &messageLimitedServerStream{
+		ServerStream: stream,
+		fullMethod:   fullMethod,
+		mode:         o.mode,
+		recvLimiter:  recvLimiter,
+		sendLimiter:  sendLimiter,
+	}
+}
+
+// messageLimitedServerStream throttles the messages of a stream before they are received or sent.
+type messageLimitedServerStream struct {
+	grpc.ServerStream
+	fullMethod  string
+	mode        Mode
+	recvLimiter Limiter
+	sendLimiter Limiter
+}
+
+func (s *messageLimitedServerStream) RecvMsg(m interface{}) error {
+	if err := throttle(s.Context(), s.recvLimiter, s.mode, "message received on "+s.fullMethod); err != nil {
+		return err
+	}
+	return s.ServerStream.RecvMsg(m)
+}
+
+func (s *messageLimitedServerStream) SendMsg(m interface{}) error {
+	if s.sendLimiter != nil {
+		if err := throttle(s.Context(), s.sendLimiter, s.mode, "message sent on "+s.fullMethod); err != nil {
+			return err
+		}
+	}
+	return s.ServerStream.SendMsg(m)
+}
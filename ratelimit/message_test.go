@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// countingServerStream counts the messages received and sent on it.
+type countingServerStream struct {
+	fakeServerStream
+	received int
+	sent     int
+}
+
+func (s *countingServerStream) RecvMsg(interface{}) error {
+	s.received++
+	return nil
+}
+
+func (s *countingServerStream) SendMsg(interface{}) error {
+	s.sent++
+	return nil
+}
+
+func newCountingServerStream(ctx context.Context) *countingServerStream {
+	return &countingServerStream{fakeServerStream: fakeServerStream{ctx: ctx}}
+}
+
+func onePerHour() Limiter {
+	return NewFixedWindow(1, time.Hour)
+}
+
+func TestMessageStreamServerInterceptor_FailFast(t *testing.T) {
+	interceptor := MessageStreamServerInterceptor(onePerHour, WithMessageMode(FailFast))
+	for i := 0; i < 2; i++ {
+		stream := newCountingServerStream(context.TODO())
+		err := interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: pingMethod}, func(srv interface{}, stream grpc.ServerStream) error {
+			require.NoError(t, stream.RecvMsg(nil), "messages within the limit must be received")
+			err := stream.RecvMsg(nil)
+			assert.Equal(t, codes.ResourceExhausted, status.Code(err), "messages beyond the limit must abort the stream")
+			assert.NoError(t, stream.SendMsg(nil), "sent messages must not be limited by default")
+			assert.NoError(t, stream.SendMsg(nil), "sent messages must not be limited by default")
+			return err
+		})
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		assert.Equal(t, 1, stream.received, "rejected messages must not be received, on stream %d", i)
+		assert.Equal(t, 2, stream.sent)
+	}
+}
+
+func TestMessageStreamServerInterceptor_Wait(t *testing.T) {
+	interceptor := MessageStreamServerInterceptor(func() Limiter { return NewTokenBucket(100, 1) })
+	stream := newCountingServerStream(context.TODO())
+	startTime := time.Now()
+	err := interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: pingMethod}, func(srv interface{}, stream grpc.ServerStream) error {
+		for i := 0; i < 4; i++ {
+			if err := stream.RecvMsg(nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err, "messages beyond the limit must be delayed")
+	assert.Equal(t, 4, stream.received)
+	assert.True(t, time.Since(startTime) >= 25*time.Millisecond, "messages must be delayed to the rate of the limiter, took %v", time.Since(startTime))
+}
+
+func TestMessageStreamServerInterceptor_WaitDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+	stream := LimitServerStream(newCountingServerStream(ctx), pingMethod, onePerHour())
+	require.NoError(t, stream.RecvMsg(nil))
+	err := stream.RecvMsg(nil)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "messages that cannot be received before the deadline must fail early")
+	assert.Contains(t, err.Error(), "deadline")
+}
+
+func TestLimitServerStream_SendLimit(t *testing.T) {
+	counting := newCountingServerStream(context.TODO())
+	stream := LimitServerStream(counting, pingMethod, NewFixedWindow(10, time.Hour), WithMessageMode(FailFast), WithSendLimit(onePerHour))
+	require.NoError(t, stream.SendMsg(nil))
+	err := stream.SendMsg(nil)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "sent messages must be limited by their own limiter")
+	assert.Contains(t, err.Error(), "message sent on "+pingMethod)
+	assert.Equal(t, 1, counting.sent)
+	assert.NoError(t, stream.RecvMsg(nil), "received messages must be limited separately")
+}
@@ -3,6 +3,7 @@ package ratelimit
 import (
 	"context"
 
+	grpc_middleware "github.com/rkollar/go-grpc-middleware"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -34,3 +35,42 @@ func StreamServerInterceptor(limiter Limiter) grpc.StreamServerInterceptor {
 		return handler(srv, stream)
 	}
 }
+
+// Middleware returns a new grpc_middleware.Middleware that performs request rate limiting on the server side.
+func Middleware(limiter Limiter) grpc_middleware.Middleware {
+	return grpc_middleware.Middleware{
+		UnaryServer:  UnaryServerInterceptor(limiter),
+		StreamServer: StreamServerInterceptor(limiter),
+	}
+}
+
+// KeyedUnaryServerInterceptor returns a new unary server interceptor that performs request rate limiting with
+// the knowledge of the call.
+func KeyedUnaryServerInterceptor(limiter KeyedLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if limiter.Limit(ctx, info.FullMethod, req) {
+			return nil, status.Errorf(codes.ResourceExhausted, "%s is rejected by grpc_ratelimit middleware, please retry later.", info.FullMethod)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// KeyedStreamServerInterceptor returns a new stream server interceptor that performs rate limiting on the request
+// with the knowledge of the call.
+func KeyedStreamServerInterceptor(limiter KeyedLimiter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if limiter.Limit(stream.Context(), info.FullMethod, nil) {
+			return status.Errorf(codes.ResourceExhausted, "%s is rejected by grpc_ratelimit middleware, please retry later.", info.FullMethod)
+		}
+		return handler(srv, stream)
+	}
+}
+
+// KeyedMiddleware returns a new grpc_middleware.Middleware that performs request rate limiting with the knowledge
+// of the call on the server side.
+func KeyedMiddleware(limiter KeyedLimiter) grpc_middleware.Middleware {
+	return grpc_middleware.Middleware{
+		UnaryServer:  KeyedUnaryServerInterceptor(limiter),
+		StreamServer: KeyedStreamServerInterceptor(limiter),
+	}
+}
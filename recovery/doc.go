@@ -10,6 +10,9 @@ By default a panic will be converted into a gRPC error with `code.Internal`.
 
 Handling can be customised by providing an alternate recovery function.
 
+The recovery middleware should be first in the chain, so that panics in all other middleware are recovered
+from too. `Stage` declares this position for chains of `grpc_middleware.Stage`.
+
 Please see examples for simple examples of use.
 */
 package grpc_recovery
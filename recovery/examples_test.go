@@ -4,8 +4,8 @@
 package grpc_recovery_test
 
 import (
-	"github.com/grpc-ecosystem/go-grpc-middleware"
-	"github.com/grpc-ecosystem/go-grpc-middleware/recovery"
+	"github.com/rkollar/go-grpc-middleware"
+	"github.com/rkollar/go-grpc-middleware/recovery"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -25,8 +25,8 @@ func Example_initialization() {
 	opts := []grpc_recovery.Option{
 		grpc_recovery.WithRecoveryHandler(customFunc),
 	}
-	// Create a server. Recovery handlers should typically be last in the chain so that other middleware
-	// (e.g. logging) can operate on the recovered state instead of being directly affected by any panic
+	// Create a server. Recovery handlers should be first in the chain, so that panics in any other middleware
+	// are recovered from too
 	_ = grpc.NewServer(
 		grpc_middleware.WithUnaryServerChain(
 			grpc_recovery.UnaryServerInterceptor(opts...),
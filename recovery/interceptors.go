@@ -6,6 +6,7 @@ package grpc_recovery
 import (
 	"context"
 
+	grpc_middleware "github.com/rkollar/go-grpc-middleware"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -60,3 +61,24 @@ func recoverFrom(ctx context.Context, p interface{}, r RecoveryHandlerFuncContex
 	}
 	return r(ctx, p)
 }
+
+// Middleware returns a new grpc_middleware.Middleware for panic recovery on the server side.
+func Middleware(opts ...Option) grpc_middleware.Middleware {
+	return grpc_middleware.Middleware{
+		UnaryServer:  UnaryServerInterceptor(opts...),
+		StreamServer: StreamServerInterceptor(opts...),
+	}
+}
+
+// StageName is the name of the grpc_recovery stage in chains of grpc_middleware.Stage.
+const StageName = "grpc_recovery"
+
+// Stage returns the Middleware of this package as a grpc_middleware.Stage, which must run before all
+// other stages so that panics in any of them are recovered from.
+func Stage(opts ...Option) grpc_middleware.Stage {
+	return grpc_middleware.Stage{
+		Name:       StageName,
+		Middleware: Middleware(opts...),
+		Before:     []string{grpc_middleware.AnyStage},
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_middleware
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCType is the type of a gRPC call.
+type GRPCType string
+
+const (
+	Unary        GRPCType = "unary"
+	ClientStream GRPCType = "client_stream"
+	ServerStream GRPCType = "server_stream"
+	BidiStream   GRPCType = "bidi_stream"
+)
+
+// CallMeta describes the gRPC call being reported on.
+type CallMeta struct {
+	// FullMethod is the full method name of the call, e.g. `/mwitkow.testproto.TestService/Ping`.
+	FullMethod string
+	// Service is the fully qualified name of the service, e.g. `mwitkow.testproto.TestService`.
+	Service string
+	// Method is the name of the method, e.g. `Ping`.
+	Method string
+	// Type is the type of the call.
+	Type GRPCType
+	// IsClient is true if the call is reported on the client side.
+	IsClient bool
+	// Peer is the remote peer of the call. It is only known upfront on the server side, and nil otherwise.
+	Peer *peer.Peer
+}
+
+func newCallMeta(ctx context.Context, fullMethod string, grpcType GRPCType, isClient bool) CallMeta {
+	meta := CallMeta{FullMethod: fullMethod, Type: grpcType, IsClient: isClient}
+	meta.Service, meta.Method = splitMethodName(fullMethod)
+	if !isClient {
+		if p, ok := peer.FromContext(ctx); ok {
+			meta.Peer = p
+		}
+	}
+	return meta
+}
+
+// splitMethodName splits a full method name of the form "/package.Service/Method" into service and method names.
+func splitMethodName(fullMethod string) (service string, method string) {
+	name := strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+func streamType(isClientStream, isServerStream bool) GRPCType {
+	switch {
+	case isClientStream && isServerStream:
+		return BidiStream
+	case isClientStream:
+		return ClientStream
+	case isServerStream:
+		return ServerStream
+	}
+	return Unary
+}
+
+// Reporter receives the events of a single gRPC call.
+//
+// For unary calls the request and response are reported as a single received and sent message (or the
+// other way around on the client side). Durations of messages are measured from the start of the call.
+type Reporter interface {
+	// PostCall is called once the call is finished, with its error, gRPC code and total duration.
+	PostCall(err error, code codes.Code, duration time.Duration)
+	// PostMsgSend is called after each message is sent.
+	PostMsgSend(msg interface{}, err error, duration time.Duration)
+	// PostMsgReceive is called after each message is received.
+	PostMsgReceive(msg interface{}, err error, duration time.Duration)
+}
+
+// PreCallFunc is the pluggable function that is called before each call, and returns the Reporter for it.
+//
+// The returned context will be propagated to handlers (on the server side) and invokers (on the client
+// side). Please make sure that the `Context` returned is a child `Context` of the one passed in.
+type PreCallFunc func(ctx context.Context, meta CallMeta) (Reporter, context.Context)
+
+// ReportingUnaryServerInterceptor returns a new unary server interceptor that reports the call to the
+// Reporter returned by preCall.
+func ReportingUnaryServerInterceptor(preCall PreCallFunc) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		startTime := time.Now()
+		reporter, newCtx := preCall(ctx, newCallMeta(ctx, info.FullMethod, Unary, false))
+		reporter.PostMsgReceive(req, nil, time.Since(startTime))
+		resp, err := handler(newCtx, req)
+		reporter.PostMsgSend(resp, err, time.Since(startTime))
+		reporter.PostCall(err, status.Code(err), time.Since(startTime))
+		return resp, err
+	}
+}
+
+// ReportingStreamServerInterceptor returns a new streaming server interceptor that reports the call and
+// all of its messages to the Reporter returned by preCall.
+func ReportingStreamServerInterceptor(preCall PreCallFunc) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		startTime := time.Now()
+		meta := newCallMeta(stream.Context(), info.FullMethod, streamType(info.IsClientStream, info.IsServerStream), false)
+		reporter, newCtx := preCall(stream.Context(), meta)
+		err := handler(srv, &reportingServerStream{ServerStream: stream, ctx: newCtx, reporter: reporter, startTime: startTime})
+		reporter.PostCall(err, status.Code(err), time.Since(startTime))
+		return err
+	}
+}
+
+// ReportingUnaryClientInterceptor returns a new unary client interceptor that reports the call to the
+// Reporter returned by preCall.
+func ReportingUnaryClientInterceptor(preCall PreCallFunc) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		startTime := time.Now()
+		reporter, newCtx := preCall(ctx, newCallMeta(ctx, method, Unary, true))
+		err := invoker(newCtx, method, req, reply, cc, opts...)
+		reporter.PostMsgSend(req, err, time.Since(startTime))
+		reporter.PostMsgReceive(reply, err, time.Since(startTime))
+		reporter.PostCall(err, status.Code(err), time.Since(startTime))
+		return err
+	}
+}
+
+// ReportingStreamClientInterceptor returns a new streaming client interceptor that reports the call and
+// all of its messages to the Reporter returned by preCall.
+//
+// The call is reported as finished once receiving a message fails, including with io.EOF at the end of
+// the stream, once the response of a client streaming call is received, when the context of the call is
+// done, or when the stream could not be established. As the context may be done at any time, PostCall may
+// then be called concurrently with the reporting of a message.
+//
+// Waiting for the context of a stream takes a goroutine, which only exits once the stream is reported as
+// finished. As grpc.ClientConn requires anyway, callers must either cancel the context of streams or read
+// them until an error, otherwise the goroutine leaks. Streams with a context that cannot be cancelled, e.g.
+// context.Background(), are only reported as finished by receiving.
+func ReportingStreamClientInterceptor(preCall PreCallFunc) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		startTime := time.Now()
+		reporter, newCtx := preCall(ctx, newCallMeta(ctx, method, streamType(desc.ClientStreams, desc.ServerStreams), true))
+		clientStream, err := streamer(newCtx, desc, cc, method, opts...)
+		if err != nil {
+			reporter.PostCall(err, status.Code(err), time.Since(startTime))
+			return nil, err
+		}
+		var once sync.Once
+		finished := make(chan struct{})
+		postCall := func(err error) {
+			once.Do(func() {
+				if err == io.EOF {
+					err = nil
+				}
+				reporter.PostCall(err, status.Code(err), time.Since(startTime))
+				close(finished)
+			})
+		}
+		if done := newCtx.Done(); done != nil {
+			go func() {
+				select {
+				case <-done:
+					postCall(contextStatusError(newCtx))
+				case <-finished:
+				}
+			}()
+		}
+		wrapped := WrapClientStream(clientStream)
+		wrapped.OnSendMsg = func(m interface{}, err error) {
+			reporter.PostMsgSend(m, err, time.Since(startTime))
+		}
+		wrapped.OnRecvMsg = func(m interface{}, err error) {
+			if err == io.EOF {
+				postCall(err)
+				return
+			}
+			reporter.PostMsgReceive(m, err, time.Since(startTime))
+			// Client streaming calls finish with their single response, without a trailing io.EOF.
+			if err != nil || !desc.ServerStreams {
+				postCall(err)
+			}
+		}
+		return wrapped, nil
+	}
+}
+
+// contextStatusError returns the gRPC status error of a done context.
+func contextStatusError(ctx context.Context) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return status.Error(codes.DeadlineExceeded, ctx.Err().Error())
+	}
+	return status.Error(codes.Canceled, ctx.Err().Error())
+}
+
+// ReportingMiddleware returns a new Middleware with all reporting interceptors using the given preCall.
+func ReportingMiddleware(preCall PreCallFunc) Middleware {
+	return Middleware{
+		UnaryServer:  ReportingUnaryServerInterceptor(preCall),
+		StreamServer: ReportingStreamServerInterceptor(preCall),
+		UnaryClient:  ReportingUnaryClientInterceptor(preCall),
+		StreamClient: ReportingStreamClientInterceptor(preCall),
+	}
+}
+
+// reportingServerStream is a grpc.ServerStream that reports all sent and received messages.
+type reportingServerStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	reporter  Reporter
+	startTime time.Time
+}
+
+func (s *reportingServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *reportingServerStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	s.reporter.PostMsgSend(m, err, time.Since(s.startTime))
+	return err
+}
+
+func (s *reportingServerStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err != io.EOF {
+		s.reporter.PostMsgReceive(m, err, time.Since(s.startTime))
+	}
+	return err
+}
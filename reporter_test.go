@@ -0,0 +1,197 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_middleware
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const reportedMethod = "/mwitkow.testproto.TestService/PingList"
+
+type recordingReporter struct {
+	meta   CallMeta
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recordingReporter) PostCall(err error, code codes.Code, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, fmt.Sprintf("call: %v", code))
+}
+
+func (r *recordingReporter) recorded() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+func (r *recordingReporter) PostMsgSend(msg interface{}, err error, duration time.Duration) {
+	r.events = append(r.events, fmt.Sprintf("send: %v %v", msg, status.Code(err)))
+}
+
+func (r *recordingReporter) PostMsgReceive(msg interface{}, err error, duration time.Duration) {
+	r.events = append(r.events, fmt.Sprintf("recv: %v %v", msg, status.Code(err)))
+}
+
+func (r *recordingReporter) preCall(ctx context.Context, meta CallMeta) (Reporter, context.Context) {
+	r.meta = meta
+	r.events = append(r.events, "pre")
+	return r, context.WithValue(ctx, "reporter", someValue)
+}
+
+func TestReportingUnaryServerInterceptor(t *testing.T) {
+	r := &recordingReporter{}
+	interceptor := ReportingUnaryServerInterceptor(r.preCall)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		requireContextValue(t, ctx, "reporter", "handler must see the context returned by preCall")
+		return "output", nil
+	}
+	out, err := interceptor(parentContext, "input", &grpc.UnaryServerInfo{FullMethod: reportedMethod}, handler)
+	require.NoError(t, err)
+	assert.Equal(t, "output", out, "interceptor must return handler's output")
+	assert.Equal(t, []string{"pre", "recv: input OK", "send: output OK", "call: OK"}, r.events)
+	assert.Equal(t, CallMeta{
+		FullMethod: reportedMethod,
+		Service:    "mwitkow.testproto.TestService",
+		Method:     "PingList",
+		Type:       Unary,
+	}, r.meta, "call meta must describe the call")
+}
+
+func TestReportingStreamServerInterceptor(t *testing.T) {
+	r := &recordingReporter{}
+	interceptor := ReportingStreamServerInterceptor(r.preCall)
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		requireContextValue(t, stream.Context(), "reporter", "handler must see the context returned by preCall")
+		require.NoError(t, stream.RecvMsg("input"))
+		require.NoError(t, stream.SendMsg("output"))
+		return status.Errorf(codes.Aborted, "aborted")
+	}
+	stream := &fakeServerStream{ctx: parentContext, recvMessage: "input"}
+	err := interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: reportedMethod, IsServerStream: true}, handler)
+	assert.Equal(t, codes.Aborted, status.Code(err), "interceptor must return handler's error")
+	assert.Equal(t, []string{"pre", "recv: input OK", "send: output OK", "call: Aborted"}, r.events)
+	assert.Equal(t, ServerStream, r.meta.Type, "call meta must describe the call")
+	assert.False(t, r.meta.IsClient, "call meta must describe the call")
+}
+
+func TestReportingUnaryClientInterceptor(t *testing.T) {
+	r := &recordingReporter{}
+	interceptor := ReportingUnaryClientInterceptor(r.preCall)
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		requireContextValue(t, ctx, "reporter", "invoker must see the context returned by preCall")
+		return nil
+	}
+	require.NoError(t, interceptor(parentContext, reportedMethod, "input", "output", nil, invoker))
+	assert.Equal(t, []string{"pre", "send: input OK", "recv: output OK", "call: OK"}, r.events)
+	assert.True(t, r.meta.IsClient, "call meta must describe the call")
+	assert.Equal(t, Unary, r.meta.Type, "call meta must describe the call")
+}
+
+func TestReportingStreamClientInterceptor(t *testing.T) {
+	r := &recordingReporter{}
+	interceptor := ReportingStreamClientInterceptor(r.preCall)
+	fake := &fakeClientStream{ctx: parentContext}
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		requireContextValue(t, ctx, "reporter", "streamer must see the context returned by preCall")
+		return fake, nil
+	}
+	desc := &grpc.StreamDesc{ClientStreams: true, ServerStreams: true}
+	stream, err := interceptor(parentContext, desc, nil, reportedMethod, streamer)
+	require.NoError(t, err)
+	require.NoError(t, stream.SendMsg("input"))
+	assert.Error(t, stream.RecvMsg("output"), "fake stream must fail on receive")
+	assert.Error(t, stream.RecvMsg("output"), "fake stream must fail on receive")
+	assert.Equal(t, []string{"pre", "send: input OK", "recv: output NotFound", "call: NotFound", "recv: output NotFound"}, r.events,
+		"call must be reported as finished only once")
+	assert.Equal(t, BidiStream, r.meta.Type, "call meta must describe the call")
+}
+
+func TestReportingStreamClientInterceptor_EOF(t *testing.T) {
+	r := &recordingReporter{}
+	interceptor := ReportingStreamClientInterceptor(r.preCall)
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &eofClientStream{fakeClientStream{ctx: ctx}}, nil
+	}
+	stream, err := interceptor(parentContext, &grpc.StreamDesc{ServerStreams: true}, nil, reportedMethod, streamer)
+	require.NoError(t, err)
+	assert.Equal(t, io.EOF, stream.RecvMsg("output"))
+	assert.Equal(t, []string{"pre", "call: OK"}, r.events, "end of stream must be reported as a successful call")
+}
+
+func TestReportingStreamClientInterceptor_ClientStreaming(t *testing.T) {
+	r := &recordingReporter{}
+	interceptor := ReportingStreamClientInterceptor(r.preCall)
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &fakeClientStream{ctx: ctx, recvMessage: "output"}, nil
+	}
+	stream, err := interceptor(parentContext, &grpc.StreamDesc{ClientStreams: true}, nil, reportedMethod, streamer)
+	require.NoError(t, err)
+	require.NoError(t, stream.SendMsg("input"))
+	require.NoError(t, stream.CloseSend())
+	require.NoError(t, stream.RecvMsg("output"))
+	assert.Equal(t, []string{"pre", "send: input OK", "recv: output OK", "call: OK"}, r.recorded(),
+		"client streaming call must be reported as finished once its response is received")
+}
+
+func TestReportingStreamClientInterceptor_Cancelled(t *testing.T) {
+	r := &recordingReporter{}
+	interceptor := ReportingStreamClientInterceptor(r.preCall)
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &fakeClientStream{ctx: ctx}, nil
+	}
+	ctx, cancel := context.WithCancel(parentContext)
+	_, err := interceptor(ctx, &grpc.StreamDesc{ServerStreams: true}, nil, reportedMethod, streamer)
+	require.NoError(t, err)
+	cancel()
+	require.Eventually(t, func() bool { return len(r.recorded()) == 2 }, time.Second, time.Millisecond,
+		"cancelled stream must be reported as finished")
+	assert.Equal(t, []string{"pre", "call: Canceled"}, r.recorded())
+}
+
+func TestReportingStreamClientInterceptor_NonCancellableContext(t *testing.T) {
+	r := &recordingReporter{}
+	interceptor := ReportingStreamClientInterceptor(r.preCall)
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &fakeClientStream{ctx: ctx}, nil
+	}
+	goroutines := runtime.NumGoroutine()
+	for i := 0; i < 10; i++ {
+		_, err := interceptor(context.Background(), &grpc.StreamDesc{ServerStreams: true}, nil, reportedMethod, streamer)
+		require.NoError(t, err)
+	}
+	assert.True(t, runtime.NumGoroutine() <= goroutines, "streams that cannot be cancelled must not leave goroutines waiting for their context")
+}
+
+func TestReportingStreamClientInterceptor_StreamerError(t *testing.T) {
+	r := &recordingReporter{}
+	interceptor := ReportingStreamClientInterceptor(r.preCall)
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return nil, status.Errorf(codes.Unavailable, "unavailable")
+	}
+	_, err := interceptor(parentContext, &grpc.StreamDesc{ServerStreams: true}, nil, reportedMethod, streamer)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, []string{"pre", "call: Unavailable"}, r.events, "failure to establish the stream must be reported")
+}
+
+type eofClientStream struct {
+	fakeClientStream
+}
+
+func (s *eofClientStream) RecvMsg(m interface{}) error {
+	return io.EOF
+}
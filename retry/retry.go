@@ -10,7 +10,8 @@ import (
 	"sync"
 	"time"
 
-	"github.com/grpc-ecosystem/go-grpc-middleware/util/metautils"
+	grpc_middleware "github.com/rkollar/go-grpc-middleware"
+	"github.com/rkollar/go-grpc-middleware/util/metautils"
 	"golang.org/x/net/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -130,6 +131,17 @@ func StreamClientInterceptor(optFuncs ...CallOption) grpc.StreamClientIntercepto
 	}
 }
 
+// Middleware returns a new grpc_middleware.Middleware that retries unary and server streaming calls
+// on the client side.
+//
+// The default configuration is to not retry *at all*, see UnaryClientInterceptor.
+func Middleware(optFuncs ...CallOption) grpc_middleware.Middleware {
+	return grpc_middleware.Middleware{
+		UnaryClient:  UnaryClientInterceptor(optFuncs...),
+		StreamClient: StreamClientInterceptor(optFuncs...),
+	}
+}
+
 // type serverStreamingRetryingStream is the implementation of grpc.ClientStream that acts as a
 // proxy to the underlying call. If any of the RecvMsg() calls fail, it will try to reestablish
 // a new ClientStream according to the retry policy.
@@ -0,0 +1,56 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_middleware
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// RouteUnaryServer creates a single interceptor that dispatches each call to the interceptor registered for its service.
+//
+// The routes are keyed by fully qualified service names, e.g. "mwitkow.testproto.TestService", and usually hold
+// chains built with ChainUnaryServer. Calls to services without a route go through defaultRoute, which may be nil
+// to call the handler directly. The routes are copied, so later changes to the map have no effect.
+func RouteUnaryServer(routes map[string]grpc.UnaryServerInterceptor, defaultRoute grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	table := make(map[string]grpc.UnaryServerInterceptor, len(routes))
+	for service, interceptor := range routes {
+		table[service] = interceptor
+	}
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		service, _ := splitMethodName(info.FullMethod)
+		interceptor, ok := table[service]
+		if !ok {
+			interceptor = defaultRoute
+		}
+		if interceptor == nil {
+			return handler(ctx, req)
+		}
+		return interceptor(ctx, req, info, handler)
+	}
+}
+
+// RouteStreamServer creates a single interceptor that dispatches each stream to the interceptor registered for its service.
+//
+// The routes are keyed by fully qualified service names, e.g. "mwitkow.testproto.TestService", and usually hold
+// chains built with ChainStreamServer. Streams of services without a route go through defaultRoute, which may be nil
+// to call the handler directly. The routes are copied, so later changes to the map have no effect.
+func RouteStreamServer(routes map[string]grpc.StreamServerInterceptor, defaultRoute grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	table := make(map[string]grpc.StreamServerInterceptor, len(routes))
+	for service, interceptor := range routes {
+		table[service] = interceptor
+	}
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		service, _ := splitMethodName(info.FullMethod)
+		interceptor, ok := table[service]
+		if !ok {
+			interceptor = defaultRoute
+		}
+		if interceptor == nil {
+			return handler(srv, stream)
+		}
+		return interceptor(srv, stream, info, handler)
+	}
+}
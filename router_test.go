@@ -0,0 +1,95 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestRouteUnaryServer(t *testing.T) {
+	var routed []string
+	route := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			routed = append(routed, name)
+			return handler(ctx, req)
+		}
+	}
+	routes := map[string]grpc.UnaryServerInterceptor{
+		"public.Api":     ChainUnaryServer(route("public-auth"), route("public-ratelimit")),
+		"internal.Admin": route("admin-auth"),
+	}
+	interceptor := RouteUnaryServer(routes, route("default"))
+	routes["other.Service"] = route("late")
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "output", nil
+	}
+
+	for _, tcase := range []struct {
+		fullMethod string
+		expected   []string
+	}{
+		{"/public.Api/Get", []string{"public-auth", "public-ratelimit"}},
+		{"/internal.Admin/Drain", []string{"admin-auth"}},
+		{"/other.Service/Get", []string{"default"}},
+		{"/public.ApiV2/Get", []string{"default"}},
+	} {
+		t.Run(tcase.fullMethod, func(t *testing.T) {
+			routed = nil
+			out, err := interceptor(parentContext, "input", &grpc.UnaryServerInfo{FullMethod: tcase.fullMethod}, handler)
+			require.NoError(t, err)
+			assert.Equal(t, "output", out, "handler's output must propagate through the router")
+			assert.Equal(t, tcase.expected, routed, "call must be dispatched to the service's route")
+		})
+	}
+}
+
+func TestRouteUnaryServer_NilDefault(t *testing.T) {
+	interceptor := RouteUnaryServer(nil, nil)
+	out, err := interceptor(parentContext, "input", parentUnaryInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "output", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "output", out, "unrouted calls must reach the handler without a default route")
+}
+
+func TestRouteStreamServer(t *testing.T) {
+	var routed []string
+	route := func(name string) grpc.StreamServerInterceptor {
+		return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			routed = append(routed, name)
+			return handler(srv, stream)
+		}
+	}
+	interceptor := RouteStreamServer(map[string]grpc.StreamServerInterceptor{
+		"public.Api":     ChainStreamServer(route("public-auth"), route("public-ratelimit")),
+		"internal.Admin": route("admin-auth"),
+	}, nil)
+	handled := 0
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		handled++
+		return nil
+	}
+
+	for _, tcase := range []struct {
+		fullMethod string
+		expected   []string
+	}{
+		{"/public.Api/List", []string{"public-auth", "public-ratelimit"}},
+		{"/internal.Admin/Watch", []string{"admin-auth"}},
+		{"/other.Service/List", nil},
+	} {
+		t.Run(tcase.fullMethod, func(t *testing.T) {
+			routed = nil
+			info := &grpc.StreamServerInfo{FullMethod: tcase.fullMethod, IsServerStream: true}
+			require.NoError(t, interceptor(someServiceName, &fakeServerStream{ctx: parentContext}, info, handler))
+			assert.Equal(t, tcase.expected, routed, "stream must be dispatched to the service's route")
+		})
+	}
+	assert.Equal(t, 3, handled, "every stream must reach the handler")
+}
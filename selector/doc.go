@@ -0,0 +1,23 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`grpc_selector` allows any interceptor to be scoped to a subset of RPCs.
+
+Method Selection Middleware
+
+By default every interceptor passed to a chain is executed for every method. Wrapping an interceptor
+with a `Matcher` makes it run only for calls that match, while all other calls go straight to the next
+handler (or invoker) in the chain. This makes it easy to, for example, exempt health checks and
+reflection from authentication without hand-writing a check on `info.FullMethod`.
+
+Matchers can select calls by full method name (`MatchMethods`), by service (`MatchServices`), by
+regular expression (`MatchRegexp`) or by an arbitrary function over the context and method (`MatchFunc`).
+Use `Not` to invert any of them.
+
+All four interceptor kinds are supported, and the wrapped interceptors compose with the chaining
+functions in `grpc_middleware`.
+
+Please see examples for simple examples of use.
+*/
+package grpc_selector
@@ -0,0 +1,36 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_selector_test
+
+import (
+	"context"
+
+	"github.com/rkollar/go-grpc-middleware"
+	"github.com/rkollar/go-grpc-middleware/auth"
+	"github.com/rkollar/go-grpc-middleware/selector"
+	"github.com/rkollar/go-grpc-middleware/tags"
+	"google.golang.org/grpc"
+)
+
+func exampleAuthFunc(ctx context.Context) (context.Context, error) {
+	return ctx, nil
+}
+
+// Simple example of exempting health checks and reflection from authentication.
+func Example_exemptFromAuth() {
+	noAuth := grpc_selector.Not(grpc_selector.MatchServices(
+		"grpc.health.v1.Health",
+		"grpc.reflection.v1alpha.ServerReflection",
+	))
+	_ = grpc.NewServer(
+		grpc_middleware.WithUnaryServerChain(
+			grpc_ctxtags.UnaryServerInterceptor(),
+			grpc_selector.UnaryServerInterceptor(grpc_auth.UnaryServerInterceptor(exampleAuthFunc), noAuth),
+		),
+		grpc_middleware.WithStreamServerChain(
+			grpc_ctxtags.StreamServerInterceptor(),
+			grpc_selector.StreamServerInterceptor(grpc_auth.StreamServerInterceptor(exampleAuthFunc), noAuth),
+		),
+	)
+}
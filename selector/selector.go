@@ -0,0 +1,167 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_selector
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"google.golang.org/grpc"
+)
+
+// Matcher decides whether a wrapped interceptor should be executed for a given call.
+type Matcher interface {
+	Match(ctx context.Context, fullMethodName string) bool
+}
+
+// MatchFunc is a function that implements the Matcher interface.
+type MatchFunc func(ctx context.Context, fullMethodName string) bool
+
+// Match calls f(ctx, fullMethodName).
+func (f MatchFunc) Match(ctx context.Context, fullMethodName string) bool {
+	return f(ctx, fullMethodName)
+}
+
+// MatchMethods returns a Matcher that matches calls to any of the given full method names,
+// e.g. `/grpc.health.v1.Health/Check`.
+func MatchMethods(fullMethodNames ...string) Matcher {
+	methods := make(map[string]struct{}, len(fullMethodNames))
+	for _, m := range fullMethodNames {
+		methods[m] = struct{}{}
+	}
+	return &methodsMatcher{methods: methods, names: append([]string(nil), fullMethodNames...)}
+}
+
+type methodsMatcher struct {
+	methods map[string]struct{}
+	names   []string
+}
+
+func (m *methodsMatcher) Match(_ context.Context, fullMethodName string) bool {
+	_, ok := m.methods[fullMethodName]
+	return ok
+}
+
+func (m *methodsMatcher) String() string {
+	return "methods(" + strings.Join(m.names, ", ") + ")"
+}
+
+// MatchServices returns a Matcher that matches calls to any method of the given fully qualified
+// service names, e.g. `grpc.reflection.v1alpha.ServerReflection`.
+func MatchServices(serviceNames ...string) Matcher {
+	prefixes := make([]string, 0, len(serviceNames))
+	for _, s := range serviceNames {
+		prefixes = append(prefixes, "/"+strings.Trim(s, "/")+"/")
+	}
+	return &servicesMatcher{prefixes: prefixes, names: append([]string(nil), serviceNames...)}
+}
+
+type servicesMatcher struct {
+	prefixes []string
+	names    []string
+}
+
+func (m *servicesMatcher) Match(_ context.Context, fullMethodName string) bool {
+	for _, p := range m.prefixes {
+		if strings.HasPrefix(fullMethodName, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *servicesMatcher) String() string {
+	return "services(" + strings.Join(m.names, ", ") + ")"
+}
+
+// MatchRegexp returns a Matcher that matches calls whose full method name matches the regular expression.
+func MatchRegexp(re *regexp.Regexp) Matcher {
+	return &regexpMatcher{re: re}
+}
+
+type regexpMatcher struct {
+	re *regexp.Regexp
+}
+
+func (m *regexpMatcher) Match(_ context.Context, fullMethodName string) bool {
+	return m.re.MatchString(fullMethodName)
+}
+
+func (m *regexpMatcher) String() string {
+	return "regexp(" + m.re.String() + ")"
+}
+
+// Not returns a Matcher that matches all calls that are not matched by m.
+func Not(m Matcher) Matcher {
+	return &notMatcher{m: m}
+}
+
+type notMatcher struct {
+	m Matcher
+}
+
+func (n *notMatcher) Match(ctx context.Context, fullMethodName string) bool {
+	return !n.m.Match(ctx, fullMethodName)
+}
+
+func (n *notMatcher) String() string {
+	return "not(" + Describe(n.m) + ")"
+}
+
+// Describe returns a human readable description of the matcher, for use in debug listings.
+//
+// All matchers of this package describe themselves, while other matchers are described by their
+// String method if they implement fmt.Stringer, and as "func" otherwise.
+func Describe(m Matcher) string {
+	if s, ok := m.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return "func"
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor that executes the given interceptor
+// only for calls matched by the matcher.
+func UnaryServerInterceptor(interceptor grpc.UnaryServerInterceptor, matcher Matcher) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !matcher.Match(ctx, info.FullMethod) {
+			return handler(ctx, req)
+		}
+		return interceptor(ctx, req, info, handler)
+	}
+}
+
+// StreamServerInterceptor returns a new streaming server interceptor that executes the given interceptor
+// only for calls matched by the matcher.
+func StreamServerInterceptor(interceptor grpc.StreamServerInterceptor, matcher Matcher) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !matcher.Match(stream.Context(), info.FullMethod) {
+			return handler(srv, stream)
+		}
+		return interceptor(srv, stream, info, handler)
+	}
+}
+
+// UnaryClientInterceptor returns a new unary client interceptor that executes the given interceptor
+// only for calls matched by the matcher.
+func UnaryClientInterceptor(interceptor grpc.UnaryClientInterceptor, matcher Matcher) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if !matcher.Match(ctx, method) {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		return interceptor(ctx, method, req, reply, cc, invoker, opts...)
+	}
+}
+
+// StreamClientInterceptor returns a new streaming client interceptor that executes the given interceptor
+// only for calls matched by the matcher.
+func StreamClientInterceptor(interceptor grpc.StreamClientInterceptor, matcher Matcher) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if !matcher.Match(ctx, method) {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+		return interceptor(ctx, desc, cc, method, streamer, opts...)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_selector
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+const (
+	pingMethod   = "/mwitkow.testproto.TestService/Ping"
+	healthMethod = "/grpc.health.v1.Health/Check"
+)
+
+func TestMatchers(t *testing.T) {
+	ctx := context.TODO()
+	for _, run := range []struct {
+		matcher Matcher
+		method  string
+		match   bool
+		msg     string
+	}{
+		{MatchMethods(healthMethod), healthMethod, true, "must match exact method"},
+		{MatchMethods(healthMethod), pingMethod, false, "must not match other methods"},
+		{MatchServices("grpc.health.v1.Health"), healthMethod, true, "must match methods of the service"},
+		{MatchServices("grpc.health.v1.Health"), "/grpc.health.v1.HealthCheck/Check", false, "must not match services sharing a prefix"},
+		{MatchRegexp(regexp.MustCompile(`/Ping$`)), pingMethod, true, "must match regexp"},
+		{MatchRegexp(regexp.MustCompile(`/Ping$`)), healthMethod, false, "must not match regexp"},
+		{Not(MatchMethods(healthMethod)), pingMethod, true, "must invert a non-match"},
+		{Not(MatchMethods(healthMethod)), healthMethod, false, "must invert a match"},
+	} {
+		assert.Equal(t, run.match, run.matcher.Match(ctx, run.method), run.msg)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	for _, run := range []struct {
+		matcher  Matcher
+		expected string
+	}{
+		{MatchMethods(healthMethod, pingMethod), "methods(/grpc.health.v1.Health/Check, /mwitkow.testproto.TestService/Ping)"},
+		{MatchServices("grpc.health.v1.Health"), "services(grpc.health.v1.Health)"},
+		{MatchRegexp(regexp.MustCompile(`/Ping$`)), "regexp(/Ping$)"},
+		{Not(MatchServices("grpc.health.v1.Health")), "not(services(grpc.health.v1.Health))"},
+		{Not(MatchFunc(func(context.Context, string) bool { return true })), "not(func)"},
+	} {
+		assert.Equal(t, run.expected, Describe(run.matcher), "matcher must describe itself")
+	}
+}
+
+func TestMatchFunc_SeesContext(t *testing.T) {
+	ctx := context.WithValue(context.TODO(), "skip", true)
+	m := MatchFunc(func(ctx context.Context, fullMethodName string) bool {
+		return ctx.Value("skip") == nil
+	})
+	assert.False(t, m.Match(ctx, pingMethod), "match func must see the call context")
+	assert.True(t, m.Match(context.TODO(), pingMethod), "match func must see the call context")
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	called := false
+	inner := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		called = true
+		return handler(ctx, req)
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "output", nil
+	}
+	interceptor := UnaryServerInterceptor(inner, Not(MatchMethods(healthMethod)))
+
+	out, err := interceptor(context.TODO(), "input", &grpc.UnaryServerInfo{FullMethod: healthMethod}, handler)
+	require.NoError(t, err)
+	assert.Equal(t, "output", out, "handler must be called for skipped methods")
+	assert.False(t, called, "interceptor must be skipped for unmatched methods")
+
+	out, err = interceptor(context.TODO(), "input", &grpc.UnaryServerInfo{FullMethod: pingMethod}, handler)
+	require.NoError(t, err)
+	assert.Equal(t, "output", out, "handler must be called for matched methods")
+	assert.True(t, called, "interceptor must be called for matched methods")
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	called := false
+	inner := func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		called = true
+		return handler(srv, stream)
+	}
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		return nil
+	}
+	stream := &fakeServerStream{ctx: context.TODO()}
+	interceptor := StreamServerInterceptor(inner, MatchServices("mwitkow.testproto.TestService"))
+
+	require.NoError(t, interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: healthMethod}, handler))
+	assert.False(t, called, "interceptor must be skipped for unmatched methods")
+
+	require.NoError(t, interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: pingMethod}, handler))
+	assert.True(t, called, "interceptor must be called for matched methods")
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	called := false
+	inner := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		called = true
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	invoked := 0
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		invoked++
+		return nil
+	}
+	interceptor := UnaryClientInterceptor(inner, MatchMethods(pingMethod))
+
+	require.NoError(t, interceptor(context.TODO(), healthMethod, nil, nil, nil, invoker))
+	assert.False(t, called, "interceptor must be skipped for unmatched methods")
+
+	require.NoError(t, interceptor(context.TODO(), pingMethod, nil, nil, nil, invoker))
+	assert.True(t, called, "interceptor must be called for matched methods")
+	assert.Equal(t, 2, invoked, "invoker must be called for all methods")
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	called := false
+	inner := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		called = true
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return nil, nil
+	}
+	interceptor := StreamClientInterceptor(inner, MatchMethods(pingMethod))
+
+	_, err := interceptor(context.TODO(), &grpc.StreamDesc{}, nil, healthMethod, streamer)
+	require.NoError(t, err)
+	assert.False(t, called, "interceptor must be skipped for unmatched methods")
+
+	_, err = interceptor(context.TODO(), &grpc.StreamDesc{}, nil, pingMethod, streamer)
+	require.NoError(t, err)
+	assert.True(t, called, "interceptor must be called for matched methods")
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (f *fakeServerStream) Context() context.Context {
+	return f.ctx
+}
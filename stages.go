@@ -0,0 +1,187 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_middleware
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AnyStage can be used in Stage.Before and Stage.After to refer to all other stages of a chain.
+//
+// For example, a stage that is Before AnyStage must be the first (outermost) stage of a chain.
+const AnyStage = "*"
+
+// Stage is a named piece of Middleware that declares where it needs to be placed in a chain.
+//
+// Ordering is expressed in terms of execution order, i.e. a stage that runs "before" another one is
+// placed to its left in the chain and sees the request first.
+type Stage struct {
+	// Name identifies the stage in the chain, and must be unique within it.
+	Name string
+	// Middleware is installed at the position of the stage.
+	Middleware Middleware
+	// After lists the stages that must run before this one, if they are part of the chain.
+	After []string
+	// Before lists the stages that must run after this one, if they are part of the chain.
+	Before []string
+	// Requires lists the stages that must be part of the chain and run before this one.
+	Requires []string
+}
+
+// ValidateStages checks that the stages, in the given order, satisfy all of their declared constraints.
+//
+// The returned error describes the first violated constraint.
+func ValidateStages(stages ...Stage) error {
+	positions, err := stagePositions(stages)
+	if err != nil {
+		return err
+	}
+	for i, s := range stages {
+		for _, r := range s.Requires {
+			if _, ok := positions[r]; !ok {
+				return fmt.Errorf("grpc_middleware: stage %q requires stage %q, which is not in the chain", s.Name, r)
+			}
+		}
+		for _, edge := range stageEdges(stages, i) {
+			if positions[edge.before] > positions[edge.after] {
+				return fmt.Errorf("grpc_middleware: stage %q must run before stage %q, but is at position %d and %q at position %d",
+					edge.before, edge.after, positions[edge.before], edge.after, positions[edge.after])
+			}
+		}
+	}
+	return nil
+}
+
+// SortStages returns the stages reordered so that all of their declared constraints are satisfied.
+//
+// The sort is stable: stages that are not constrained relative to each other keep their given order.
+// An error is returned if a required stage is missing or the constraints contradict each other.
+func SortStages(stages ...Stage) ([]Stage, error) {
+	positions, err := stagePositions(stages)
+	if err != nil {
+		return nil, err
+	}
+	n := len(stages)
+	successors := make([][]int, n)
+	inDegree := make([]int, n)
+	for i, s := range stages {
+		for _, r := range s.Requires {
+			if _, ok := positions[r]; !ok {
+				return nil, fmt.Errorf("grpc_middleware: stage %q requires stage %q, which is not in the chain", s.Name, r)
+			}
+		}
+		for _, edge := range stageEdges(stages, i) {
+			from, to := positions[edge.before], positions[edge.after]
+			successors[from] = append(successors[from], to)
+			inDegree[to]++
+		}
+	}
+	sorted := make([]Stage, 0, n)
+	done := make([]bool, n)
+	for len(sorted) < n {
+		next := -1
+		for i := 0; i < n; i++ {
+			if !done[i] && inDegree[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			var cycle []string
+			for i, s := range stages {
+				if !done[i] {
+					cycle = append(cycle, s.Name)
+				}
+			}
+			return nil, fmt.Errorf("grpc_middleware: contradicting ordering constraints between stages %s", strings.Join(cycle, ", "))
+		}
+		done[next] = true
+		sorted = append(sorted, stages[next])
+		for _, succ := range successors[next] {
+			inDegree[succ]--
+		}
+	}
+	return sorted, nil
+}
+
+// ChainStages validates the order of the stages, and creates a single Middleware out of them.
+//
+// Use SortStages first to have the stages reordered instead of failing.
+func ChainStages(stages ...Stage) (Middleware, error) {
+	if err := ValidateStages(stages...); err != nil {
+		return Middleware{}, err
+	}
+	mw := make([]Middleware, 0, len(stages))
+	for _, s := range stages {
+		mw = append(mw, s.Middleware)
+	}
+	return ChainMiddleware(mw...), nil
+}
+
+func stagePositions(stages []Stage) (map[string]int, error) {
+	positions := make(map[string]int, len(stages))
+	for i, s := range stages {
+		if s.Name == "" || s.Name == AnyStage {
+			return nil, fmt.Errorf("grpc_middleware: stage at position %d has an invalid name %q", i, s.Name)
+		}
+		if _, ok := positions[s.Name]; ok {
+			return nil, fmt.Errorf("grpc_middleware: stage %q is in the chain more than once", s.Name)
+		}
+		positions[s.Name] = i
+	}
+	return positions, nil
+}
+
+// stageEdge is a constraint that stage `before` runs before stage `after`.
+type stageEdge struct {
+	before, after string
+}
+
+// stageEdges returns the constraints declared by the stage at index i that apply to stages in the chain.
+func stageEdges(stages []Stage, i int) []stageEdge {
+	s := stages[i]
+	var edges []stageEdge
+	for _, a := range append(append([]string(nil), s.Requires...), s.After...) {
+		if a == AnyStage {
+			for _, other := range stages {
+				if other.Name != s.Name && !containsStage(other.After, AnyStage) {
+					edges = append(edges, stageEdge{before: other.Name, after: s.Name})
+				}
+			}
+		} else if stageIndex(stages, a) >= 0 {
+			edges = append(edges, stageEdge{before: a, after: s.Name})
+		}
+	}
+	for _, b := range s.Before {
+		if b == AnyStage {
+			for _, other := range stages {
+				if other.Name != s.Name && !containsStage(other.Before, AnyStage) {
+					edges = append(edges, stageEdge{before: s.Name, after: other.Name})
+				}
+			}
+		} else if stageIndex(stages, b) >= 0 {
+			edges = append(edges, stageEdge{before: s.Name, after: b})
+		}
+	}
+	return edges
+}
+
+func stageIndex(stages []Stage, name string) int {
+	for i, s := range stages {
+		if s.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func containsStage(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+var (
+	tagsStage     = Stage{Name: "tags"}
+	loggingStage  = Stage{Name: "logging", After: []string{"tags"}}
+	payloadStage  = Stage{Name: "payload", Requires: []string{"logging"}}
+	recoveryStage = Stage{Name: "recovery", Before: []string{AnyStage}}
+	authStage     = Stage{Name: "auth", Before: []string{"payload"}}
+)
+
+func stageNames(stages []Stage) []string {
+	var names []string
+	for _, s := range stages {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func TestValidateStages(t *testing.T) {
+	for _, run := range []struct {
+		stages []Stage
+		err    string
+		msg    string
+	}{
+		{
+			stages: []Stage{recoveryStage, tagsStage, loggingStage, authStage, payloadStage},
+			msg:    "correctly ordered stages must be valid",
+		},
+		{
+			stages: []Stage{loggingStage, payloadStage},
+			msg:    "soft constraints on missing stages must be ignored",
+		},
+		{
+			stages: []Stage{loggingStage, tagsStage},
+			err:    `grpc_middleware: stage "tags" must run before stage "logging", but is at position 1 and "logging" at position 0`,
+			msg:    "after constraints must be checked",
+		},
+		{
+			stages: []Stage{loggingStage, payloadStage, authStage},
+			err:    `grpc_middleware: stage "auth" must run before stage "payload", but is at position 2 and "payload" at position 1`,
+			msg:    "before constraints must be checked",
+		},
+		{
+			stages: []Stage{tagsStage, payloadStage},
+			err:    `grpc_middleware: stage "payload" requires stage "logging", which is not in the chain`,
+			msg:    "required stages must be present",
+		},
+		{
+			stages: []Stage{payloadStage, loggingStage},
+			err:    `grpc_middleware: stage "logging" must run before stage "payload", but is at position 1 and "payload" at position 0`,
+			msg:    "required stages must run before",
+		},
+		{
+			stages: []Stage{tagsStage, recoveryStage},
+			err:    `grpc_middleware: stage "recovery" must run before stage "tags", but is at position 1 and "tags" at position 0`,
+			msg:    "AnyStage constraints must be checked",
+		},
+		{
+			stages: []Stage{tagsStage, tagsStage},
+			err:    `grpc_middleware: stage "tags" is in the chain more than once`,
+			msg:    "stage names must be unique",
+		},
+	} {
+		err := ValidateStages(run.stages...)
+		if run.err == "" {
+			assert.NoError(t, err, run.msg)
+		} else {
+			assert.EqualError(t, err, run.err, run.msg)
+		}
+	}
+}
+
+func TestSortStages(t *testing.T) {
+	sorted, err := SortStages(payloadStage, authStage, loggingStage, tagsStage, recoveryStage)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"recovery", "auth", "tags", "logging", "payload"}, stageNames(sorted),
+		"stages must be sorted to satisfy constraints, keeping the given order otherwise")
+	require.NoError(t, ValidateStages(sorted...), "sorted stages must be valid")
+
+	unconstrained := []Stage{{Name: "c"}, {Name: "a"}, {Name: "b"}}
+	sorted, err = SortStages(unconstrained...)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "a", "b"}, stageNames(sorted), "unconstrained stages must keep their order")
+}
+
+func TestSortStages_Errors(t *testing.T) {
+	_, err := SortStages(tagsStage, payloadStage)
+	assert.EqualError(t, err, `grpc_middleware: stage "payload" requires stage "logging", which is not in the chain`)
+
+	_, err = SortStages(Stage{Name: "a", After: []string{"b"}}, Stage{Name: "b", After: []string{"a"}}, tagsStage)
+	assert.EqualError(t, err, `grpc_middleware: contradicting ordering constraints between stages a, b`)
+}
+
+func TestChainStages(t *testing.T) {
+	var calls []string
+	named := func(s Stage) Stage {
+		name := s.Name
+		s.Middleware = Middleware{
+			UnaryServer: func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+				calls = append(calls, name)
+				return handler(ctx, req)
+			},
+		}
+		return s
+	}
+	_, err := ChainStages(named(loggingStage), named(tagsStage))
+	assert.Error(t, err, "misordered stages must not be chained")
+
+	mw, err := ChainStages(named(tagsStage), named(loggingStage))
+	require.NoError(t, err)
+	_, err = mw.UnaryServer(parentContext, "input", parentUnaryInfo, noopUnaryHandler)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tags", "logging"}, calls, "stages must be chained in order")
+}
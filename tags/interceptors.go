@@ -9,7 +9,7 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/peer"
 
-	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/rkollar/go-grpc-middleware"
 )
 
 // UnaryServerInterceptor returns a new unary server interceptors that sets the values for request tags.
@@ -83,3 +83,22 @@ func setRequestFieldTags(ctx context.Context, f RequestFieldExtractorFunc, fullM
 		}
 	}
 }
+
+// Middleware returns a new grpc_middleware.Middleware that sets the values for request tags on the server side.
+func Middleware(opts ...Option) grpc_middleware.Middleware {
+	return grpc_middleware.Middleware{
+		UnaryServer:  UnaryServerInterceptor(opts...),
+		StreamServer: StreamServerInterceptor(opts...),
+	}
+}
+
+// StageName is the name of the grpc_ctxtags stage in chains of grpc_middleware.Stage.
+const StageName = "grpc_ctxtags"
+
+// Stage returns the Middleware of this package as a grpc_middleware.Stage.
+func Stage(opts ...Option) grpc_middleware.Stage {
+	return grpc_middleware.Stage{
+		Name:       StageName,
+		Middleware: Middleware(opts...),
+	}
+}
@@ -16,7 +16,7 @@ import (
 	"net"
 	"time"
 
-	pb_testproto "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
+	pb_testproto "github.com/rkollar/go-grpc-middleware/testing/testproto"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"google.golang.org/grpc"
@@ -28,6 +28,9 @@ var (
 
 	certPEM []byte
 	keyPEM  []byte
+
+	clientCA    *x509.Certificate
+	clientCAKey *rsa.PrivateKey
 )
 
 // InterceptorTestSuite is a testify/Suite that starts a gRPC PingService server and a client.
@@ -58,6 +61,10 @@ func (s *InterceptorTestSuite) SetupSuite() {
 	if err != nil {
 		s.T().Fatalf("unable to generate test certificate/key: " + err.Error())
 	}
+	clientCA, clientCAKey, err = generateCA("client-ca.example.com")
+	if err != nil {
+		s.T().Fatalf("unable to generate test client CA: " + err.Error())
+	}
 	go func() {
 		for {
 			var err error
@@ -72,7 +79,14 @@ func (s *InterceptorTestSuite) SetupSuite() {
 				if err != nil {
 					s.T().Fatalf("unable to load test TLS certificate: %v", err)
 				}
-				creds := credentials.NewServerTLSFromCert(&cert)
+				// Client certificates are optional, so that only tests of mTLS need to present them.
+				clientCAs := x509.NewCertPool()
+				clientCAs.AddCert(clientCA)
+				creds := credentials.NewTLS(&tls.Config{
+					Certificates: []tls.Certificate{cert},
+					ClientCAs:    clientCAs,
+					ClientAuth:   tls.VerifyClientCertIfGiven,
+				})
 				s.ServerOpts = append(s.ServerOpts, grpc.Creds(creds))
 			}
 			// This is the point where we hook up the interceptor
@@ -112,13 +126,38 @@ func (s *InterceptorTestSuite) RestartServer(delayedStart time.Duration) <-chan
 }
 
 func (s *InterceptorTestSuite) NewClient(dialOpts ...grpc.DialOption) pb_testproto.TestServiceClient {
+	return s.newClient(nil, dialOpts...)
+}
+
+// NewClientWithCert creates a client that presents the certificate to the server, see NewClientCert.
+//
+// The certificate is only presented if the suite uses TLS.
+func (s *InterceptorTestSuite) NewClientWithCert(cert tls.Certificate, dialOpts ...grpc.DialOption) pb_testproto.TestServiceClient {
+	return s.newClient(&cert, dialOpts...)
+}
+
+// NewClientCert creates a client certificate that the server of the suite trusts.
+//
+// The subject, SANs and validity of the certificate are taken from the template, while its serial number,
+// keys and usages are filled in.
+func (s *InterceptorTestSuite) NewClientCert(template *x509.Certificate) tls.Certificate {
+	cert, err := generateClientCert(template, clientCA, clientCAKey)
+	require.NoError(s.T(), err, "must be able to generate a client certificate")
+	return cert
+}
+
+func (s *InterceptorTestSuite) newClient(cert *tls.Certificate, dialOpts ...grpc.DialOption) pb_testproto.TestServiceClient {
 	newDialOpts := append(dialOpts, grpc.WithBlock())
 	if *flagTls {
 		cp := x509.NewCertPool()
 		if !cp.AppendCertsFromPEM(certPEM) {
 			s.T().Fatal("failed to append certificate")
 		}
-		creds := credentials.NewTLS(&tls.Config{ServerName: "localhost", RootCAs: cp})
+		config := &tls.Config{ServerName: "localhost", RootCAs: cp}
+		if cert != nil {
+			config.Certificates = []tls.Certificate{*cert}
+		}
+		creds := credentials.NewTLS(config)
 		newDialOpts = append(newDialOpts, grpc.WithTransportCredentials(creds))
 	} else {
 		newDialOpts = append(newDialOpts, grpc.WithInsecure())
@@ -197,3 +236,60 @@ func generateCertAndKey(san []string) ([]byte, []byte, error) {
 
 	return certOut, keyOut, nil
 }
+
+// generateCA creates a self-signed certificate authority for client certificates.
+func generateCA(commonName string) (*x509.Certificate, *rsa.PrivateKey, error) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	template := x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	derBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, priv.Public(), priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	ca, err := x509.ParseCertificate(derBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ca, priv, nil
+}
+
+// generateClientCert creates a client certificate from the template, signed by the CA.
+func generateClientCert(template *x509.Certificate, ca *x509.Certificate, caKey *rsa.PrivateKey) (tls.Certificate, error) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	certTemplate := *template
+	certTemplate.SerialNumber = serialNumber
+	certTemplate.KeyUsage = x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature
+	certTemplate.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	if certTemplate.NotBefore.IsZero() {
+		certTemplate.NotBefore = time.Now().Add(-time.Minute)
+	}
+	if certTemplate.NotAfter.IsZero() {
+		certTemplate.NotAfter = time.Now().Add(time.Hour)
+	}
+	derBytes, err := x509.CreateCertificate(rand.Reader, &certTemplate, ca, priv.Public(), caKey)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{derBytes}, PrivateKey: priv}, nil
+}
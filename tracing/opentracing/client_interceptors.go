@@ -8,10 +8,11 @@ import (
 	"io"
 	"sync"
 
-	"github.com/grpc-ecosystem/go-grpc-middleware/util/metautils"
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
 	"github.com/opentracing/opentracing-go/log"
+	grpc_middleware "github.com/rkollar/go-grpc-middleware"
+	"github.com/rkollar/go-grpc-middleware/util/metautils"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/grpclog"
 	"google.golang.org/grpc/metadata"
@@ -47,57 +48,37 @@ func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
 			finishClientSpan(clientSpan, err)
 			return nil, err
 		}
-		return &tracedClientStream{ClientStream: clientStream, clientSpan: clientSpan}, nil
+		return newTracedClientStream(clientStream, clientSpan), nil
 	}
 }
 
-// type serverStreamingRetryingStream is the implementation of grpc.ClientStream that acts as a
-// proxy to the underlying call. If any of the RecvMsg() calls fail, it will try to reestablish
-// a new ClientStream according to the retry policy.
-type tracedClientStream struct {
-	grpc.ClientStream
-	mu              sync.Mutex
-	alreadyFinished bool
-	clientSpan      opentracing.Span
-}
-
-func (s *tracedClientStream) Header() (metadata.MD, error) {
-	h, err := s.ClientStream.Header()
-	if err != nil {
-		s.finishClientSpan(err)
+// newTracedClientStream wraps the grpc.ClientStream so that the client span is finished once the
+// stream fails or is closed by the client.
+func newTracedClientStream(clientStream grpc.ClientStream, clientSpan opentracing.Span) grpc.ClientStream {
+	var once sync.Once
+	finish := func(err error) {
+		once.Do(func() {
+			finishClientSpan(clientSpan, err)
+		})
 	}
-	return h, err
-}
-
-func (s *tracedClientStream) SendMsg(m interface{}) error {
-	err := s.ClientStream.SendMsg(m)
-	if err != nil {
-		s.finishClientSpan(err)
+	wrapped := grpc_middleware.WrapClientStream(clientStream)
+	wrapped.OnHeader = func(_ metadata.MD, err error) {
+		if err != nil {
+			finish(err)
+		}
 	}
-	return err
-}
-
-func (s *tracedClientStream) CloseSend() error {
-	err := s.ClientStream.CloseSend()
-	s.finishClientSpan(err)
-	return err
-}
-
-func (s *tracedClientStream) RecvMsg(m interface{}) error {
-	err := s.ClientStream.RecvMsg(m)
-	if err != nil {
-		s.finishClientSpan(err)
+	wrapped.OnSendMsg = func(_ interface{}, err error) {
+		if err != nil {
+			finish(err)
+		}
 	}
-	return err
-}
-
-func (s *tracedClientStream) finishClientSpan(err error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	if !s.alreadyFinished {
-		finishClientSpan(s.clientSpan, err)
-		s.alreadyFinished = true
+	wrapped.OnCloseSend = finish
+	wrapped.OnRecvMsg = func(_ interface{}, err error) {
+		if err != nil {
+			finish(err)
+		}
 	}
+	return wrapped
 }
 
 // ClientAddContextTags returns a context with specified opentracing tags, which
@@ -3,8 +3,8 @@ package grpc_opentracing
 import (
 	"strings"
 
-	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
 	opentracing "github.com/opentracing/opentracing-go"
+	grpc_ctxtags "github.com/rkollar/go-grpc-middleware/tags"
 	"google.golang.org/grpc/grpclog"
 )
 
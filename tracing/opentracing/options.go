@@ -5,7 +5,10 @@ package grpc_opentracing
 
 import (
 	"context"
+
 	"github.com/opentracing/opentracing-go"
+	grpc_middleware "github.com/rkollar/go-grpc-middleware"
+	"github.com/rkollar/go-grpc-middleware/tags"
 )
 
 var (
@@ -75,3 +78,16 @@ func WithUnaryRequestHandlerFunc(f UnaryRequestHandlerFunc) Option {
 		o.unaryRequestHandlerFunc = f
 	}
 }
+
+// StageName is the name of the grpc_opentracing stage in chains of grpc_middleware.Stage.
+const StageName = "grpc_opentracing"
+
+// Stage returns the Middleware of this package as a grpc_middleware.Stage, which runs after the
+// grpc_ctxtags stage so that trace IDs can be injected into the request tags.
+func Stage(opts ...Option) grpc_middleware.Stage {
+	return grpc_middleware.Stage{
+		Name:       StageName,
+		Middleware: Middleware(opts...),
+		After:      []string{grpc_ctxtags.StageName},
+	}
+}
@@ -6,6 +6,7 @@ package grpc_validator
 import (
 	"context"
 
+	grpc_middleware "github.com/rkollar/go-grpc-middleware"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -71,3 +72,13 @@ func (s *recvWrapper) RecvMsg(m interface{}) error {
 	}
 	return nil
 }
+
+// Middleware returns a new grpc_middleware.Middleware that validates incoming messages on the server
+// side and outgoing unary messages on the client side.
+func Middleware() grpc_middleware.Middleware {
+	return grpc_middleware.Middleware{
+		UnaryServer:  UnaryServerInterceptor(),
+		StreamServer: StreamServerInterceptor(),
+		UnaryClient:  UnaryClientInterceptor(),
+	}
+}
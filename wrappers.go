@@ -7,6 +7,7 @@ import (
 	"context"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 // WrappedServerStream is a thin wrapper around grpc.ServerStream that allows modifying context.
@@ -28,3 +29,88 @@ func WrapServerStream(stream grpc.ServerStream) *WrappedServerStream {
 	}
 	return &WrappedServerStream{ServerStream: stream, WrappedContext: stream.Context()}
 }
+
+// WrappedClientStream is a thin wrapper around grpc.ClientStream that allows modifying context and
+// observing the calls made on the stream.
+//
+// All hooks are optional. They are called after the corresponding call on the nested grpc.ClientStream
+// returns, with its results, and cannot change them.
+type WrappedClientStream struct {
+	grpc.ClientStream
+	// WrappedContext is the wrapper's own Context. You can assign it. If nil, the nested
+	// grpc.ClientStream.Context() is used.
+	WrappedContext context.Context
+
+	// OnSendMsg is called after every SendMsg with the sent message and the resulting error.
+	OnSendMsg func(m interface{}, err error)
+	// OnRecvMsg is called after every RecvMsg with the received message and the resulting error.
+	OnRecvMsg func(m interface{}, err error)
+	// OnCloseSend is called after CloseSend with the resulting error.
+	OnCloseSend func(err error)
+	// OnHeader is called after every Header with the header metadata and the resulting error.
+	OnHeader func(md metadata.MD, err error)
+	// OnTrailer is called after every Trailer with the trailer metadata.
+	OnTrailer func(md metadata.MD)
+}
+
+// Context returns the wrapper's WrappedContext if set, overwriting the nested grpc.ClientStream.Context()
+func (w *WrappedClientStream) Context() context.Context {
+	if w.WrappedContext != nil {
+		return w.WrappedContext
+	}
+	return w.ClientStream.Context()
+}
+
+// SendMsg calls SendMsg on the nested grpc.ClientStream and then the OnSendMsg hook.
+func (w *WrappedClientStream) SendMsg(m interface{}) error {
+	err := w.ClientStream.SendMsg(m)
+	if w.OnSendMsg != nil {
+		w.OnSendMsg(m, err)
+	}
+	return err
+}
+
+// RecvMsg calls RecvMsg on the nested grpc.ClientStream and then the OnRecvMsg hook.
+func (w *WrappedClientStream) RecvMsg(m interface{}) error {
+	err := w.ClientStream.RecvMsg(m)
+	if w.OnRecvMsg != nil {
+		w.OnRecvMsg(m, err)
+	}
+	return err
+}
+
+// CloseSend calls CloseSend on the nested grpc.ClientStream and then the OnCloseSend hook.
+func (w *WrappedClientStream) CloseSend() error {
+	err := w.ClientStream.CloseSend()
+	if w.OnCloseSend != nil {
+		w.OnCloseSend(err)
+	}
+	return err
+}
+
+// Header calls Header on the nested grpc.ClientStream and then the OnHeader hook.
+func (w *WrappedClientStream) Header() (metadata.MD, error) {
+	md, err := w.ClientStream.Header()
+	if w.OnHeader != nil {
+		w.OnHeader(md, err)
+	}
+	return md, err
+}
+
+// Trailer calls Trailer on the nested grpc.ClientStream and then the OnTrailer hook.
+func (w *WrappedClientStream) Trailer() metadata.MD {
+	md := w.ClientStream.Trailer()
+	if w.OnTrailer != nil {
+		w.OnTrailer(md)
+	}
+	return md
+}
+
+// WrapClientStream returns a ClientStream that has the ability to overwrite context and observe calls.
+//
+// Unlike WrapServerStream, a new wrapper is always returned, so that hooks set by different interceptors
+// do not overwrite each other. The context of the nested stream is not read until Context is called, as
+// doing so disables transparent retries in gRPC.
+func WrapClientStream(stream grpc.ClientStream) *WrappedClientStream {
+	return &WrappedClientStream{ClientStream: stream}
+}
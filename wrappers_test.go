@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
@@ -22,6 +23,49 @@ func TestWrapServerStream(t *testing.T) {
 	assert.NotNil(t, wrapped.Context().Value("other"), "values from wrapper must be set")
 }
 
+func TestWrapClientStream(t *testing.T) {
+	ctx := context.WithValue(context.TODO(), "something", 1)
+	fake := &fakeClientStream{ctx: ctx}
+	wrapped := WrapClientStream(fake)
+	assert.NotNil(t, wrapped.Context().Value("something"), "values from fake must propagate to wrapper")
+	wrapped.WrappedContext = context.WithValue(wrapped.Context(), "other", 2)
+	assert.NotNil(t, wrapped.Context().Value("other"), "values from wrapper must be set")
+	assert.NotEqual(t, wrapped, WrapClientStream(wrapped), "wrapping must always return a new wrapper")
+}
+
+func TestWrapClientStream_Hooks(t *testing.T) {
+	fake := &fakeClientStream{ctx: context.TODO()}
+	wrapped := WrapClientStream(fake)
+	var sent, recv interface{}
+	var recvErr, closeErr error
+	var header, trailer metadata.MD
+	closed := false
+	wrapped.OnSendMsg = func(m interface{}, err error) { sent = m }
+	wrapped.OnRecvMsg = func(m interface{}, err error) { recv, recvErr = m, err }
+	wrapped.OnCloseSend = func(err error) { closed, closeErr = true, err }
+	wrapped.OnHeader = func(md metadata.MD, err error) { header = md }
+	wrapped.OnTrailer = func(md metadata.MD) { trailer = md }
+
+	assert.NoError(t, wrapped.SendMsg("sent"), "SendMsg must be passed to the nested stream")
+	assert.Equal(t, "sent", fake.sentMessage, "SendMsg must be passed to the nested stream")
+	assert.Equal(t, "sent", sent, "OnSendMsg must see the sent message")
+
+	err := wrapped.RecvMsg("received")
+	assert.Equal(t, codes.NotFound, status.Code(err), "RecvMsg must return the nested stream's error")
+	assert.Equal(t, "received", recv, "OnRecvMsg must see the received message")
+	assert.Equal(t, err, recvErr, "OnRecvMsg must see the nested stream's error")
+
+	assert.NoError(t, wrapped.CloseSend(), "CloseSend must be passed to the nested stream")
+	assert.True(t, fake.closedSend, "CloseSend must be passed to the nested stream")
+	assert.True(t, closed, "OnCloseSend must be called")
+	assert.NoError(t, closeErr, "OnCloseSend must see the nested stream's error")
+
+	md, err := wrapped.Header()
+	assert.NoError(t, err, "Header must be passed to the nested stream")
+	assert.Equal(t, md, header, "OnHeader must see the header metadata")
+	assert.Equal(t, wrapped.Trailer(), trailer, "OnTrailer must see the trailer metadata")
+}
+
 type fakeServerStream struct {
 	grpc.ServerStream
 	ctx         context.Context
@@ -50,4 +94,40 @@ func (f *fakeServerStream) RecvMsg(m interface{}) error {
 
 type fakeClientStream struct {
 	grpc.ClientStream
+	ctx         context.Context
+	recvMessage interface{}
+	sentMessage interface{}
+	closedSend  bool
+}
+
+func (f *fakeClientStream) Context() context.Context {
+	return f.ctx
+}
+
+func (f *fakeClientStream) SendMsg(m interface{}) error {
+	if f.sentMessage != nil {
+		return status.Errorf(codes.AlreadyExists, "fakeClientStream only takes one message, sorry")
+	}
+	f.sentMessage = m
+	return nil
+}
+
+func (f *fakeClientStream) RecvMsg(m interface{}) error {
+	if f.recvMessage == nil {
+		return status.Errorf(codes.NotFound, "fakeClientStream has no message, sorry")
+	}
+	return nil
+}
+
+func (f *fakeClientStream) CloseSend() error {
+	f.closedSend = true
+	return nil
+}
+
+func (f *fakeClientStream) Header() (metadata.MD, error) {
+	return metadata.Pairs("header", "value"), nil
+}
+
+func (f *fakeClientStream) Trailer() metadata.MD {
+	return metadata.Pairs("trailer", "value")
 }